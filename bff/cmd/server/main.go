@@ -15,16 +15,19 @@ import (
 // @contact.url https://github.com/sefikcan
 // @BasePath /api/v1
 // @host localhost:50050
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	log.Println("Starting bff api server")
 
-	cfg := config.NewConfig()
+	cfg, remoteConfig := config.NewConfig()
 
 	zapLogger := logger.NewLogger(cfg)
 	zapLogger.InitLogger()
-	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, false)
+	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, cfg.Server.SSL)
 
-	s := internal.NewServer(cfg, zapLogger)
+	s := internal.NewServer(cfg, remoteConfig, zapLogger)
 	if err := s.Run(); err != nil {
 		log.Fatal(err)
 	}
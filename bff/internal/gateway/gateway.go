@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/internal/middlewares"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"strings"
+)
+
+// Gateway serves the REST routes grpc-gateway generates from the google.api.http annotations in product.proto.
+// Calls go through the same connection as the hand written handlers, so timeouts, retries and the circuit
+// breaker apply. Roles are required per method like on the hand written routes, and enforced again by the product
+// service from the forwarded principal.
+type Gateway struct {
+	cfg    *config.Config
+	logger logger.Logger
+	mux    *runtime.ServeMux
+}
+
+func (g *Gateway) Handle() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		req = req.WithContext(util.GetGrpcCtx(c))
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, g.cfg.Gateway.Prefix)
+		req.URL.RawPath = ""
+
+		g.mux.ServeHTTP(c.Response(), req)
+		return nil
+	}
+}
+
+func (g *Gateway) OpenAPI() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, pb.OpenAPI)
+	}
+}
+
+func MapGatewayRoutes(gatewayRouteGroup *echo.Group, g *Gateway, mw *middlewares.MiddlewareManager) {
+	gatewayRouteGroup.GET("/*", g.Handle(), mw.RequireRole(auth.RoleViewer))
+	gatewayRouteGroup.POST("/*", g.Handle(), mw.RequireRole(auth.RoleEditor))
+	gatewayRouteGroup.PUT("/*", g.Handle(), mw.RequireRole(auth.RoleEditor))
+	gatewayRouteGroup.DELETE("/*", g.Handle(), mw.RequireRole(auth.RoleAdmin))
+}
+
+// dropHeaders keeps the gateway from forwarding request headers on its own, the metadata of GetGrpcCtx
+// already carries what the product service accepts.
+func dropHeaders(string) (string, bool) {
+	return "", false
+}
+
+// outgoingMetadata hands the metadata GetGrpcCtx prepared from the echo context to the generated handlers,
+// which would otherwise start a fresh outgoing context.
+func outgoingMetadata(_ context.Context, req *http.Request) metadata.MD {
+	md, _ := metadata.FromOutgoingContext(req.Context())
+	return md
+}
+
+func (g *Gateway) errorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, req *http.Request, err error) {
+	g.logger.FromContext(req.Context()).Errorf("Error, IPAddress: %s, Error: %s", req.RemoteAddr, err)
+
+	util.SetRetryAfter(w.Header(), err)
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, req, err)
+}
+
+func NewGateway(ctx context.Context, cfg *config.Config, logger logger.Logger, conn *grpc.ClientConn) (*Gateway, error) {
+	g := &Gateway{
+		cfg:    cfg,
+		logger: logger,
+	}
+
+	g.mux = runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(dropHeaders),
+		runtime.WithMetadata(outgoingMetadata),
+		runtime.WithErrorHandler(g.errorHandler),
+	)
+	if err := pb.RegisterProductServiceHandler(ctx, g.mux, conn); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
@@ -0,0 +1,12 @@
+package responses
+
+type HealthResponse struct {
+	Status       string                        `json:"status"`
+	Dependencies map[string]DependencyResponse `json:"dependencies,omitempty"`
+}
+
+type DependencyResponse struct {
+	Status          string `json:"status"`
+	ConnectionState string `json:"connectionState,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
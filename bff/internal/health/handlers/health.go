@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/internal/health/dto/responses"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statusUp   = "UP"
+	statusDown = "DOWN"
+
+	productServiceDependency = "productService"
+)
+
+type HealthHandlers interface {
+	Live() echo.HandlerFunc
+	Ready() echo.HandlerFunc
+	// Shutdown fails readiness from now on so the instance is taken out of rotation before it stops
+	Shutdown()
+}
+
+type healthHandlers struct {
+	cfg          *config.Config
+	logger       logger.Logger
+	conn         *grpc.ClientConn
+	healthClient healthpb.HealthClient
+	shuttingDown *atomic.Bool
+}
+
+// Live godoc
+// @Summary Liveness
+// @Description Reports that the process is up, dependencies are not checked
+// @Tags Health
+// @Produce json
+// @Success 200 {object} responses.HealthResponse
+// @Router /health/live [get]
+func (h healthHandlers) Live() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, responses.HealthResponse{Status: statusUp})
+	}
+}
+
+// Ready godoc
+// @Summary Readiness
+// @Description Reports whether the product service connection and its dependencies are serving
+// @Tags Health
+// @Produce json
+// @Success 200 {object} responses.HealthResponse
+// @Failure 503 {object} responses.HealthResponse
+// @Router /health/ready [get]
+func (h healthHandlers) Ready() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.shuttingDown.Load() {
+			return c.JSON(http.StatusServiceUnavailable, responses.HealthResponse{Status: statusDown})
+		}
+
+		productService := h.checkProductService(c.Request().Context())
+
+		res := responses.HealthResponse{
+			Status: statusUp,
+			Dependencies: map[string]responses.DependencyResponse{
+				productServiceDependency: productService,
+			},
+		}
+
+		if productService.Status != statusUp {
+			h.logger.FromContext(c.Request().Context()).Warnf("Readiness check failed, Dependency: %s, Error: %s", productServiceDependency, productService.Error)
+			res.Status = statusDown
+			return c.JSON(http.StatusServiceUnavailable, res)
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+func (h healthHandlers) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h healthHandlers) checkProductService(ctx context.Context) responses.DependencyResponse {
+	state := h.conn.GetState()
+	if state == connectivity.Idle {
+		h.conn.Connect()
+	}
+
+	res := responses.DependencyResponse{
+		Status:          statusDown,
+		ConnectionState: state.String(),
+	}
+	if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+		res.Error = "connection is not usable"
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.cfg.Server.CtxTimeout)*time.Second)
+	defer cancel()
+
+	check, err := h.healthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: pb.ProductService_ServiceDesc.ServiceName})
+	res.ConnectionState = h.conn.GetState().String()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if check.Status != healthpb.HealthCheckResponse_SERVING {
+		res.Error = "product service is " + check.Status.String()
+		return res
+	}
+
+	res.Status = statusUp
+	return res
+}
+
+func NewHealthHandler(cfg *config.Config, logger logger.Logger, conn *grpc.ClientConn) HealthHandlers {
+	return &healthHandlers{
+		cfg:          cfg,
+		logger:       logger,
+		conn:         conn,
+		healthClient: healthpb.NewHealthClient(conn),
+		shuttingDown: &atomic.Bool{},
+	}
+}
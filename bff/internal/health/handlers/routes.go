@@ -0,0 +1,9 @@
+package handlers
+
+import "github.com/labstack/echo/v4"
+
+func MapHealthRoutes(healthRouteGroup *echo.Group, h HealthHandlers) {
+	healthRouteGroup.GET("", h.Ready())
+	healthRouteGroup.GET("/live", h.Live())
+	healthRouteGroup.GET("/ready", h.Ready())
+}
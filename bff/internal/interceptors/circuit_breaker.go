@@ -0,0 +1,89 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/circuitbreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// breakerFailureCodes are the codes that say the product service itself is unhealthy, the rest are answers
+var breakerFailureCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DeadlineExceeded: true,
+}
+
+// CircuitBreakerUnaryInterceptor fails calls fast with Unavailable while the product service keeps failing
+// and lets probes through once the open timeout has passed. State changes and rejections are exported as metrics.
+func (im *InterceptorManager) CircuitBreakerUnaryInterceptor() (grpc.UnaryClientInterceptor, error) {
+	name := im.cfg.Metric.ServiceName
+	target := im.cfg.ClientsConfig.ProductServiceClientUrl
+
+	state := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_circuit_breaker_state",
+		Help: "0 closed, 1 open, 2 half-open",
+	}, []string{"target"})
+	if err := prometheus.Register(state); err != nil {
+		return nil, err
+	}
+
+	transitions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_circuit_breaker_transitions",
+	}, []string{"target", "from", "to"})
+	if err := prometheus.Register(transitions); err != nil {
+		return nil, err
+	}
+
+	rejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_circuit_breaker_rejected",
+	}, []string{"target", "method"})
+	if err := prometheus.Register(rejected); err != nil {
+		return nil, err
+	}
+
+	breakerCfg := im.cfg.ClientsConfig.ProductServiceCircuitBreaker
+	breaker := circuitbreaker.NewBreaker(circuitbreaker.Settings{
+		FailureThreshold: breakerCfg.FailureThreshold,
+		OpenTimeout:      time.Duration(breakerCfg.OpenTimeout) * time.Millisecond,
+		HalfOpenRequests: breakerCfg.HalfOpenRequests,
+		OnStateChange: func(from, to circuitbreaker.State) {
+			im.logger.Warnf("Circuit breaker state changed, Target: %s, From: %s, To: %s", target, from, to)
+			state.WithLabelValues(target).Set(float64(to))
+			transitions.WithLabelValues(target, from.String(), to.String()).Inc()
+		},
+	})
+	state.WithLabelValues(target).Set(float64(circuitbreaker.Closed))
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !breakerCfg.Enabled {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		done, err := breaker.Allow()
+		if err != nil {
+			rejected.WithLabelValues(target, method).Inc()
+			return status.Error(codes.Unavailable, err.Error())
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		switch {
+		case err == nil:
+			done(circuitbreaker.Success)
+		case errors.Is(ctx.Err(), context.Canceled):
+			// the caller went away, that says nothing about the product service
+			done(circuitbreaker.Ignored)
+		case breakerFailureCodes[status.Code(err)]:
+			done(circuitbreaker.Failure)
+		default:
+			done(circuitbreaker.Success)
+		}
+
+		return err
+	}, nil
+}
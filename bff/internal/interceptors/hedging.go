@@ -0,0 +1,91 @@
+package interceptors
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"time"
+)
+
+// HedgingUnaryInterceptor sends another attempt of a configured read every Delay until one succeeds or
+// MaxAttempts are in flight, the first successful reply wins and the other attempts are cancelled.
+// A non-fatal failure sends the next attempt right away, any other failure fails the call.
+func (im *InterceptorManager) HedgingUnaryInterceptor() grpc.UnaryClientInterceptor {
+	hedgingCfg := im.cfg.ClientsConfig.ProductServiceHedging
+
+	methods := make(map[string]bool, len(hedgingCfg.Methods))
+	for _, method := range hedgingCfg.Methods {
+		methods[method] = true
+	}
+
+	nonFatal := make(map[codes.Code]bool, len(hedgingCfg.NonFatalStatusCodes))
+	for _, name := range hedgingCfg.NonFatalStatusCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			im.logger.Warnf("Ignoring hedging status code %s: %v", name, err)
+			continue
+		}
+		nonFatal[code] = true
+	}
+
+	delay := time.Duration(hedgingCfg.Delay) * time.Millisecond
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		message, ok := reply.(proto.Message)
+		if !hedgingCfg.Enabled || !methods[method] || hedgingCfg.MaxAttempts < 2 || !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type attempt struct {
+			reply proto.Message
+			err   error
+		}
+		attempts := make(chan attempt, hedgingCfg.MaxAttempts)
+		send := func() {
+			attemptReply := message.ProtoReflect().New().Interface()
+			err := invoker(ctx, method, req, attemptReply, cc, opts...)
+			attempts <- attempt{reply: attemptReply, err: err}
+		}
+
+		go send()
+		sent, pending := 1, 1
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				if sent < hedgingCfg.MaxAttempts {
+					go send()
+					sent++
+					pending++
+					timer.Reset(delay)
+				}
+			case result := <-attempts:
+				pending--
+				if result.err == nil {
+					proto.Merge(message, result.reply)
+					return nil
+				}
+				if !nonFatal[status.Code(result.err)] {
+					return result.err
+				}
+				if sent < hedgingCfg.MaxAttempts {
+					go send()
+					sent++
+					pending++
+					timer.Reset(delay)
+				} else if pending == 0 {
+					return result.err
+				}
+			}
+		}
+	}
+}
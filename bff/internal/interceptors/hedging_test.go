@@ -0,0 +1,227 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hedgedMethod is the read on the hedging allow-list, createMethod is a write left out of it
+var (
+	hedgedMethod = fullMethod("GetProductDetail")
+	createMethod = fullMethod("CreateProduct")
+)
+
+// fullMethod is the name grpc hands to the interceptors for a ProductService rpc, unknown rpcs fail the test binary
+func fullMethod(name string) string {
+	for _, method := range pb.ProductService_ServiceDesc.Methods {
+		if method.MethodName == name {
+			return "/" + pb.ProductService_ServiceDesc.ServiceName + "/" + name
+		}
+	}
+	panic(any("ProductService has no method " + name))
+}
+
+// attemptFunc plays one attempt, the nth call of the invoker runs the nth attemptFunc
+type attemptFunc func(ctx context.Context, reply *wrapperspb.StringValue) error
+
+func reply(value string) attemptFunc {
+	return func(ctx context.Context, reply *wrapperspb.StringValue) error {
+		reply.Value = value
+		return nil
+	}
+}
+
+func fail(code codes.Code) attemptFunc {
+	return func(ctx context.Context, reply *wrapperspb.StringValue) error {
+		return status.Error(code, code.String())
+	}
+}
+
+// hang answers only once ctx is done, cancelled receives the attempt index when that happens
+func hang(cancelled chan<- int, index int) attemptFunc {
+	return func(ctx context.Context, reply *wrapperspb.StringValue) error {
+		<-ctx.Done()
+		cancelled <- index
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+type fakeInvoker struct {
+	mutex    sync.Mutex
+	attempts []attemptFunc
+	calls    int
+}
+
+func (f *fakeInvoker) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	f.mutex.Lock()
+	attempt := f.attempts[f.calls]
+	f.calls++
+	f.mutex.Unlock()
+
+	return attempt(ctx, reply.(*wrapperspb.StringValue))
+}
+
+func (f *fakeInvoker) count() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.calls
+}
+
+func newHedgingInterceptor(maxAttempts int, delay time.Duration) grpc.UnaryClientInterceptor {
+	cfg := &config.Config{}
+	cfg.ClientsConfig.ProductServiceHedging = config.HedgingConfig{
+		Enabled:             true,
+		Methods:             []string{hedgedMethod},
+		MaxAttempts:         maxAttempts,
+		Delay:               int(delay / time.Millisecond),
+		NonFatalStatusCodes: []string{"UNAVAILABLE"},
+	}
+
+	return NewInterceptorManager(cfg, logger.NewLogger(cfg)).HedgingUnaryInterceptor()
+}
+
+func TestHedgingUnaryInterceptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		maxAttempts int
+		delay       time.Duration
+		attempts    func(cancelled chan<- int) []attemptFunc
+		wantValue   string
+		wantCode    codes.Code
+		wantCalls   int
+		// wantCancelled attempts have to see their context cancelled once the call returned
+		wantCancelled []int
+	}{
+		{
+			name:        "first attempt answers before the delay",
+			method:      hedgedMethod,
+			maxAttempts: 3,
+			delay:       time.Hour,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{reply("first")}
+			},
+			wantValue: "first",
+			wantCode:  codes.OK,
+			wantCalls: 1,
+		},
+		{
+			name:        "hedged attempt wins and the slow one is cancelled",
+			method:      hedgedMethod,
+			maxAttempts: 2,
+			delay:       10 * time.Millisecond,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{hang(cancelled, 0), reply("second")}
+			},
+			wantValue:     "second",
+			wantCode:      codes.OK,
+			wantCalls:     2,
+			wantCancelled: []int{0},
+		},
+		{
+			name:        "non-fatal failure sends the next attempt right away",
+			method:      hedgedMethod,
+			maxAttempts: 2,
+			delay:       time.Hour,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{fail(codes.Unavailable), reply("second")}
+			},
+			wantValue: "second",
+			wantCode:  codes.OK,
+			wantCalls: 2,
+		},
+		{
+			name:        "fatal failure cancels the pending attempts",
+			method:      hedgedMethod,
+			maxAttempts: 3,
+			delay:       10 * time.Millisecond,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{hang(cancelled, 0), fail(codes.NotFound)}
+			},
+			wantCode:      codes.NotFound,
+			wantCalls:     2,
+			wantCancelled: []int{0},
+		},
+		{
+			name:        "every attempt fails",
+			method:      hedgedMethod,
+			maxAttempts: 2,
+			delay:       time.Hour,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{fail(codes.Unavailable), fail(codes.Unavailable)}
+			},
+			wantCode:  codes.Unavailable,
+			wantCalls: 2,
+		},
+		{
+			name:        "method without hedging",
+			method:      createMethod,
+			maxAttempts: 3,
+			delay:       10 * time.Millisecond,
+			attempts: func(cancelled chan<- int) []attemptFunc {
+				return []attemptFunc{fail(codes.Unavailable)}
+			},
+			wantCode:  codes.Unavailable,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancelled := make(chan int, tt.maxAttempts)
+			invoker := &fakeInvoker{attempts: tt.attempts(cancelled)}
+			interceptor := newHedgingInterceptor(tt.maxAttempts, tt.delay)
+
+			got := &wrapperspb.StringValue{}
+			err := interceptor(context.Background(), tt.method, &wrapperspb.StringValue{}, got, nil, invoker.invoke)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %s, want %s (err %v)", code, tt.wantCode, err)
+			}
+			if got.Value != tt.wantValue {
+				t.Errorf("reply = %q, want %q", got.Value, tt.wantValue)
+			}
+			if calls := invoker.count(); calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", calls, tt.wantCalls)
+			}
+
+			for _, want := range tt.wantCancelled {
+				select {
+				case index := <-cancelled:
+					if index != want {
+						t.Errorf("cancelled attempt = %d, want %d", index, want)
+					}
+				case <-time.After(time.Second):
+					t.Errorf("attempt %d was not cancelled", want)
+				}
+			}
+		})
+	}
+}
+
+func TestHedgingUnaryInterceptorKeepsCallerCancellation(t *testing.T) {
+	cancelled := make(chan int, 2)
+	invoker := &fakeInvoker{attempts: []attemptFunc{hang(cancelled, 0), hang(cancelled, 1)}}
+	interceptor := newHedgingInterceptor(2, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := interceptor(ctx, hedgedMethod, &wrapperspb.StringValue{}, &wrapperspb.StringValue{}, nil, invoker.invoke)
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		t.Errorf("code = %s, want %s (err %v)", code, codes.DeadlineExceeded, err)
+	}
+	if calls := invoker.count(); calls != 2 {
+		t.Errorf("attempts = %d, want 2", calls)
+	}
+}
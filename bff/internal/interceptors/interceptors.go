@@ -0,0 +1,29 @@
+package interceptors
+
+import (
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"sync/atomic"
+)
+
+// InterceptorManager holds the client interceptors used on the product service connection
+type InterceptorManager struct {
+	cfg      *config.Config
+	logger   logger.Logger
+	timeouts atomic.Pointer[methodTimeouts]
+}
+
+// Reload applies the settings of cfg that may change while the connection is open
+func (im *InterceptorManager) Reload(cfg *config.Config) {
+	im.timeouts.Store(newMethodTimeouts(cfg))
+}
+
+func NewInterceptorManager(cfg *config.Config, logger logger.Logger) *InterceptorManager {
+	im := &InterceptorManager{
+		cfg:    cfg,
+		logger: logger,
+	}
+	im.Reload(cfg)
+
+	return im
+}
@@ -0,0 +1,46 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// methodTimeouts are the configured method timeouts and Server.CtxTimeout as fallback
+type methodTimeouts struct {
+	methods  map[string]time.Duration
+	fallback time.Duration
+}
+
+func newMethodTimeouts(cfg *config.Config) *methodTimeouts {
+	timeouts := &methodTimeouts{
+		methods:  make(map[string]time.Duration, len(cfg.ClientsConfig.ProductServiceTimeouts)),
+		fallback: time.Duration(cfg.Server.CtxTimeout) * time.Second,
+	}
+	for _, methodTimeout := range cfg.ClientsConfig.ProductServiceTimeouts {
+		timeouts.methods[methodTimeout.Method] = time.Duration(methodTimeout.Timeout) * time.Millisecond
+	}
+
+	return timeouts
+}
+
+// TimeoutUnaryInterceptor bounds every call by its configured method timeout, falling back to
+// Server.CtxTimeout. An earlier deadline already on ctx, like a disconnecting HTTP client, still wins.
+func (im *InterceptorManager) TimeoutUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeouts := im.timeouts.Load()
+		timeout, ok := timeouts.methods[method]
+		if !ok {
+			timeout = timeouts.fallback
+		}
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
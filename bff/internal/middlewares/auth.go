@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
+	"net/http"
+)
+
+// AuthMiddleware verifies the bearer token and stores the principal for RequireRole and the gRPC calls
+func (mw *MiddlewareManager) AuthMiddleware(verifier *auth.Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !mw.cfg.Auth.Enabled {
+				return next(c)
+			}
+
+			authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+			principal, err := verifier.VerifyAuthorization(authorization)
+			if err != nil {
+				util.PrepareLogging(c, mw.logger, err)
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return c.JSON(http.StatusUnauthorized, util.NewHttpResponse(http.StatusUnauthorized, "invalid or missing bearer token", nil))
+			}
+
+			c.Set(util.PrincipalKey, principal)
+			c.Set(util.AuthorizationKey, authorization)
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(logger.WithContext(req.Context(), mw.logger.FromContext(req.Context()).With(logger.FieldUser, principal.Subject))))
+
+			return next(c)
+		}
+	}
+}
+
+// RequireRole rejects principals without the role or a higher ranked one
+func (mw *MiddlewareManager) RequireRole(role auth.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !mw.cfg.Auth.Enabled {
+				return next(c)
+			}
+
+			principal, ok := util.GetPrincipal(c)
+			if !ok || !principal.HasRole(role) {
+				mw.logger.FromContext(c.Request().Context()).Warnf("Forbidden, Required role: %s", role)
+				return c.JSON(http.StatusForbidden, util.NewHttpResponse(http.StatusForbidden, "insufficient role", nil))
+			}
+
+			return next(c)
+		}
+	}
+}
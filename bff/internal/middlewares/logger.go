@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
+)
+
+// LoggerMiddleware stores a logger in the request context that adds the request id, trace, span and tenant
+// to every line, it has to run after the request id middleware.
+func (mw *MiddlewareManager) LoggerMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fields := []interface{}{logger.FieldRequestId, util.GetRequestId(c)}
+		if traceId := util.GetTraceId(c); traceId != "" {
+			fields = append(fields, logger.FieldTraceId, traceId, logger.FieldSpanId, util.GetSpanId(c))
+		}
+		if tenant := util.GetTenant(c); tenant != "" {
+			fields = append(fields, logger.FieldTenant, tenant)
+		}
+
+		req := c.Request()
+		c.SetRequest(req.WithContext(logger.WithContext(req.Context(), mw.logger.With(fields...))))
+
+		return next(c)
+	}
+}
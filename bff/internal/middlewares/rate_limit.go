@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/ratelimit"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = util.HeaderRetryAfter
+)
+
+func (mw *MiddlewareManager) NewRateLimiter() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(RateLimitRules(mw.cfg.RateLimit))
+}
+
+func (mw *MiddlewareManager) NewIPRateLimiter() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(IPRateLimitRules(mw.cfg.RateLimit))
+}
+
+// RateLimitRules returns the limiter rules of cfg, there are none while rate limiting is disabled
+func RateLimitRules(cfg config.RateLimitConfig) (ratelimit.Rule, map[string]ratelimit.Rule) {
+	rules := make(map[string]ratelimit.Rule, len(cfg.Routes))
+	if !cfg.Enabled {
+		return ratelimit.Rule{}, rules
+	}
+
+	for _, route := range cfg.Routes {
+		rules[route.Method+" "+route.Path] = ratelimit.Rule{Rate: route.Rate, Burst: route.Burst}
+	}
+
+	return ratelimit.Rule{Rate: cfg.Rate, Burst: cfg.Burst}, rules
+}
+
+// IPRateLimitRules returns the single rule every client IP shares across routes
+func IPRateLimitRules(cfg config.RateLimitConfig) (ratelimit.Rule, map[string]ratelimit.Rule) {
+	if !cfg.Enabled {
+		return ratelimit.Rule{}, map[string]ratelimit.Rule{}
+	}
+
+	return ratelimit.Rule{Rate: cfg.IP.Rate, Burst: cfg.IP.Burst}, map[string]ratelimit.Rule{}
+}
+
+// RouteFunc names the route a request is limited on
+type RouteFunc func(c echo.Context) string
+
+// RegisteredRoute is the method and the registered path, requests for different ids share the bucket
+func RegisteredRoute(c echo.Context) string {
+	return c.Request().Method + " " + c.Path()
+}
+
+// RequestPath is the method and the requested path, for wildcard routes that serve several endpoints
+func RequestPath(c echo.Context) string {
+	return c.Request().Method + " " + c.Request().URL.Path
+}
+
+// RateLimitMiddleware limits each client per route, it runs after AuthMiddleware to key on the JWT subject
+func (mw *MiddlewareManager) RateLimitMiddleware(limiter *ratelimit.Limiter, route RouteFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client := mw.clientIdentity(c)
+			return mw.limit(c, next, limiter.Allow(route(c), client), client)
+		}
+	}
+}
+
+// IPRateLimitMiddleware limits each client IP across every route, it runs before AuthMiddleware so requests
+// are limited before their tokens are verified
+func (mw *MiddlewareManager) IPRateLimitMiddleware(limiter *ratelimit.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client := "ip:" + c.RealIP()
+			return mw.limit(c, next, limiter.Allow("", client), client)
+		}
+	}
+}
+
+// limit sets the rate limit headers of result, and answers instead of next when the request is not allowed
+func (mw *MiddlewareManager) limit(c echo.Context, next echo.HandlerFunc, result ratelimit.Result, client string) error {
+	if result.Limit == 0 {
+		return next(c)
+	}
+
+	header := c.Response().Header()
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		header.Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		mw.logger.FromContext(c.Request().Context()).Warnf("Rate limited, Client: %s, Path: %s", client, c.Request().URL.Path)
+		return c.JSON(http.StatusTooManyRequests, util.NewHttpResponse(http.StatusTooManyRequests, "too many requests", nil))
+	}
+
+	return next(c)
+}
+
+// clientIdentity is the verified JWT subject, a configured API key or the client IP, in that order.
+// Unknown API keys are ignored, so rotating them does not hand out fresh buckets.
+func (mw *MiddlewareManager) clientIdentity(c echo.Context) string {
+	if subject := util.GetSubject(c); subject != "" {
+		return "sub:" + subject
+	}
+
+	if header := mw.cfg.RateLimit.ApiKeyHeader; header != "" {
+		if apiKey := c.Request().Header.Get(header); apiKey != "" {
+			for i, key := range mw.cfg.RateLimit.ApiKeys {
+				// the index keeps the key itself out of the logs
+				if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+					return "key:" + strconv.Itoa(i)
+				}
+			}
+		}
+	}
+
+	return "ip:" + c.RealIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
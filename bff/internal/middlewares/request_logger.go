@@ -2,7 +2,6 @@ package middlewares
 
 import (
 	"github.com/labstack/echo/v4"
-	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
 	"time"
 )
 
@@ -16,9 +15,8 @@ func (mw *MiddlewareManager) RequestLoggerMiddleware(next echo.HandlerFunc) echo
 		status := res.Status
 		size := res.Size
 		s := time.Since(start).String()
-		requestId := util.GetRequestId(c)
 
-		mw.logger.Infof("RequestId: %s, Method: %s, Url: %s, Status: %v, Size: %v, Time: %s", requestId, req.Method, req.URL, status, size, s)
+		mw.logger.FromContext(req.Context()).Infof("Method: %s, Url: %s, Status: %v, Size: %v, Time: %s", req.Method, req.URL, status, size, s)
 
 		return err
 	}
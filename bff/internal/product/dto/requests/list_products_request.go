@@ -0,0 +1,9 @@
+package requests
+
+// ListProductsRequest pages through products, PageToken is the NextPageToken of the previous page
+type ListProductsRequest struct {
+	PageSize  int32  `query:"pageSize"`
+	PageToken string `query:"pageToken"`
+	Category  string `query:"category"`
+	Query     string `query:"query"`
+}
@@ -6,3 +6,8 @@ type ProductResponse struct {
 	Category   string `json:"category"`
 	OptionName string `json:"optionName"`
 }
+
+type ProductListResponse struct {
+	Products      []*ProductResponse `json:"products"`
+	NextPageToken string             `json:"nextPageToken,omitempty"`
+}
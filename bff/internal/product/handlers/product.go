@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/sefikcan/ms-grpc-sample/bff/internal/product/dto/requests"
 	"github.com/sefikcan/ms-grpc-sample/bff/internal/product/mappers"
@@ -35,22 +34,27 @@ type productHandlers struct {
 // @Produce json
 // @Param createProductRequest body requests.CreateProductRequest true "Create Product"
 // @Success 201 {object} responses.ProductResponse
+// @Header 201 {string} X-Consistency-Token "Token to read your own write"
+// @Security BearerAuth
 // @Router /products [post]
 func (p productHandlers) Create() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		productRequest := requests.CreateProductRequest{}
 		if err := c.Bind(&productRequest); err != nil {
-			p.logger.Errorf("Error, RequestId: %s, IPAddress: %s, Error: %s", util.GetRequestId(c), util.GetIPAddress(c), err)
+			util.PrepareLogging(c, p.logger, err)
 			return c.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
 		}
 
 		clientReq := mappers.CreateProductRequestToGrpcRequestObject(productRequest)
 
-		res, err := p.c.CreateProduct(context.Background(), clientReq)
+		res, err := p.c.CreateProduct(util.GetGrpcCtx(c), clientReq)
 		if err != nil {
-			p.logger.Fatalf("Unexpected error: %v\n", err)
+			util.PrepareLogging(c, p.logger, err)
+			util.SetRetryAfter(c.Response().Header(), err)
+			return c.JSON(util.GetHttpStatusFromGrpcError(err), util.NewHttpResponseFromGrpcError(err))
 		}
 
+		util.SetConsistencyToken(c, res.ConsistencyToken)
 		return c.JSON(http.StatusCreated, mappers.CreateProductGrpcResponseToResponseObject(res))
 	}
 }
@@ -63,6 +67,9 @@ func (p productHandlers) Create() echo.HandlerFunc {
 // @Produce json
 // @Param id path string true "id"
 // @Success 204
+// @Header 204 {string} X-Consistency-Token "Token to read your own write"
+// @Failure 404 {object} util.HttpResponse
+// @Security BearerAuth
 // @Router /products/{id} [delete]
 func (p productHandlers) Delete() echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -70,11 +77,14 @@ func (p productHandlers) Delete() echo.HandlerFunc {
 			Id: c.Param("id"),
 		}
 
-		_, err := p.c.DeleteProduct(context.Background(), req)
+		res, err := p.c.DeleteProduct(util.GetGrpcCtx(c), req)
 		if err != nil {
-			p.logger.Errorf("Error happened while deleting: %v\n", err)
+			util.PrepareLogging(c, p.logger, err)
+			util.SetRetryAfter(c.Response().Header(), err)
+			return c.JSON(util.GetHttpStatusFromGrpcError(err), util.NewHttpResponseFromGrpcError(err))
 		}
 
+		util.SetConsistencyToken(c, res.ConsistencyToken)
 		return c.NoContent(http.StatusNoContent)
 	}
 }
@@ -88,23 +98,29 @@ func (p productHandlers) Delete() echo.HandlerFunc {
 // @Param id path string true "id"
 // @Param updateProductRequest body requests.UpdateProductRequest true "Update Product"
 // @Success 200 {object} responses.ProductResponse
+// @Header 200 {string} X-Consistency-Token "Token to read your own write"
+// @Failure 404 {object} util.HttpResponse
+// @Security BearerAuth
 // @Router /products/{id} [put]
 func (p productHandlers) Update() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		req := requests.UpdateProductRequest{}
 		if err := c.Bind(&req); err != nil {
-			p.logger.Errorf("Error, RequestId: %s, IPAddress: %s, Error: %s", util.GetRequestId(c), util.GetIPAddress(c), err)
+			util.PrepareLogging(c, p.logger, err)
 			return c.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
 		}
 
 		id := c.Param("id")
 
-		res, err := p.c.UpdateProduct(context.Background(), mappers.UpdateProductRequestToGrpcRequestObject(id, req))
+		res, err := p.c.UpdateProduct(util.GetGrpcCtx(c), mappers.UpdateProductRequestToGrpcRequestObject(id, req))
 		if err != nil {
-			p.logger.Errorf("Error happened while updating: %v\n", err)
+			util.PrepareLogging(c, p.logger, err)
+			util.SetRetryAfter(c.Response().Header(), err)
+			return c.JSON(util.GetHttpStatusFromGrpcError(err), util.NewHttpResponseFromGrpcError(err))
 		}
 
-		return c.JSON(http.StatusOK, res)
+		util.SetConsistencyToken(c, res.ConsistencyToken)
+		return c.JSON(http.StatusOK, mappers.UpdateProductGrpcResponseToResponseObject(res))
 	}
 }
 
@@ -115,24 +131,60 @@ func (p productHandlers) Update() echo.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param id path string true "id"
+// @Param X-Consistency-Token header string false "Token returned from a mutation"
 // @Success 200 {object} responses.ProductResponse
+// @Failure 404 {object} util.HttpResponse
+// @Security BearerAuth
 // @Router /products/{id} [get]
 func (p productHandlers) GetById() echo.HandlerFunc {
 	return func(c echo.Context) error {
-		req := &pb.GetProductDetailRequest{Id: c.Param("id")}
+		req := &pb.GetProductDetailRequest{
+			Id:               c.Param("id"),
+			ConsistencyToken: util.GetConsistencyToken(c),
+		}
 
-		res, err := p.c.GetProductDetail(context.Background(), req)
+		res, err := p.c.GetProductDetail(util.GetGrpcCtx(c), req)
 		if err != nil {
-			p.logger.Errorf("Error happened while reading: %v\n", err)
+			util.PrepareLogging(c, p.logger, err)
+			util.SetRetryAfter(c.Response().Header(), err)
+			return c.JSON(util.GetHttpStatusFromGrpcError(err), util.NewHttpResponseFromGrpcError(err))
 		}
 
 		return c.JSON(http.StatusOK, mappers.GetProductGrpcResponseToResponseObject(res))
 	}
 }
 
+// GetAll godoc
+// @Summary List products
+// @Description List products handler, pages are ordered by id
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param pageSize query int false "Page size, 20 by default and 100 at most"
+// @Param pageToken query string false "nextPageToken of the previous page"
+// @Param category query string false "Category"
+// @Param query query string false "Searches names containing it, ignoring case"
+// @Param X-Consistency-Token header string false "Token returned from a mutation"
+// @Success 200 {object} responses.ProductListResponse
+// @Failure 400 {object} util.HttpResponse
+// @Security BearerAuth
+// @Router /products [get]
 func (p productHandlers) GetAll() echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return c.JSON(http.StatusNoContent, nil)
+		req := requests.ListProductsRequest{}
+		if err := c.Bind(&req); err != nil {
+			util.PrepareLogging(c, p.logger, err)
+			return c.JSON(http.StatusBadRequest, util.NewHttpResponse(http.StatusBadRequest, strings.ToLower(err.Error()), nil))
+		}
+
+		res, err := p.c.ListProducts(util.GetGrpcCtx(c), mappers.ListProductsRequestToGrpcRequestObject(req, util.GetConsistencyToken(c)))
+		if err != nil {
+			util.PrepareLogging(c, p.logger, err)
+			util.SetRetryAfter(c.Response().Header(), err)
+			return c.JSON(util.GetHttpStatusFromGrpcError(err), util.NewHttpResponseFromGrpcError(err))
+		}
+
+		return c.JSON(http.StatusOK, mappers.ListProductsGrpcResponseToResponseObject(res))
 	}
 }
 
@@ -1,11 +1,15 @@
 package handlers
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/internal/middlewares"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
+)
 
-func MapProductRoutes(productRouteGroup *echo.Group, p ProductHandlers) {
-	productRouteGroup.POST("", p.Create())
-	productRouteGroup.PUT("/:id", p.Update())
-	productRouteGroup.DELETE("/:id", p.Delete())
-	productRouteGroup.GET("/:id", p.GetById())
-	productRouteGroup.GET("", p.GetAll())
+func MapProductRoutes(productRouteGroup *echo.Group, p ProductHandlers, mw *middlewares.MiddlewareManager) {
+	productRouteGroup.POST("", p.Create(), mw.RequireRole(auth.RoleEditor))
+	productRouteGroup.PUT("/:id", p.Update(), mw.RequireRole(auth.RoleEditor))
+	productRouteGroup.DELETE("/:id", p.Delete(), mw.RequireRole(auth.RoleAdmin))
+	productRouteGroup.GET("/:id", p.GetById(), mw.RequireRole(auth.RoleViewer))
+	productRouteGroup.GET("", p.GetAll(), mw.RequireRole(auth.RoleViewer))
 }
@@ -32,6 +32,15 @@ func CreateProductGrpcResponseToResponseObject(productResponse *pb.CreateProduct
 	}
 }
 
+func UpdateProductGrpcResponseToResponseObject(productResponse *pb.UpdateProductResponse) *responses.ProductResponse {
+	return &responses.ProductResponse{
+		Id:         productResponse.Id,
+		Name:       productResponse.Name,
+		OptionName: productResponse.OptionName,
+		Category:   productResponse.Category,
+	}
+}
+
 func GetProductGrpcResponseToResponseObject(productResponse *pb.GetProductDetailResponse) *responses.ProductResponse {
 	return &responses.ProductResponse{
 		Id:         productResponse.Id,
@@ -40,3 +49,25 @@ func GetProductGrpcResponseToResponseObject(productResponse *pb.GetProductDetail
 		Category:   productResponse.Category,
 	}
 }
+
+func ListProductsRequestToGrpcRequestObject(productRequest requests.ListProductsRequest, consistencyToken string) *pb.ListProductsRequest {
+	return &pb.ListProductsRequest{
+		PageSize:         productRequest.PageSize,
+		PageToken:        productRequest.PageToken,
+		Category:         productRequest.Category,
+		Query:            productRequest.Query,
+		ConsistencyToken: consistencyToken,
+	}
+}
+
+func ListProductsGrpcResponseToResponseObject(productsResponse *pb.ListProductsResponse) *responses.ProductListResponse {
+	products := make([]*responses.ProductResponse, 0, len(productsResponse.Products))
+	for _, product := range productsResponse.Products {
+		products = append(products, GetProductGrpcResponseToResponseObject(product))
+	}
+
+	return &responses.ProductListResponse{
+		Products:      products,
+		NextPageToken: productsResponse.NextPageToken,
+	}
+}
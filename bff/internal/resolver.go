@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+const staticScheme = "static"
+
+// productServiceTarget returns the dial target of the product service, a static endpoint list is served by a
+// manual resolver so the balancer sees every replica, otherwise ProductServiceClientUrl is resolved by grpc.
+func productServiceTarget(cfg *config.Config) (string, []grpc.DialOption) {
+	endpoints := cfg.ClientsConfig.ProductServiceEndpoints
+	if len(endpoints) == 0 {
+		return cfg.ClientsConfig.ProductServiceClientUrl, nil
+	}
+
+	addresses := make([]resolver.Address, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		addresses = append(addresses, resolver.Address{Addr: endpoint})
+	}
+
+	r := manual.NewBuilderWithScheme(staticScheme)
+	r.InitialState(resolver.State{Addresses: addresses})
+
+	return staticScheme + ":///product", []grpc.DialOption{grpc.WithResolvers(r)}
+}
@@ -2,19 +2,32 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/sefikcan/ms-grpc-sample/bff/internal/gateway"
+	healthHandlers "github.com/sefikcan/ms-grpc-sample/bff/internal/health/handlers"
+	"github.com/sefikcan/ms-grpc-sample/bff/internal/interceptors"
 	"github.com/sefikcan/ms-grpc-sample/bff/internal/middlewares"
 	"github.com/sefikcan/ms-grpc-sample/bff/internal/product/handlers"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/admin"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/metric"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/tlsconfig"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/tracing"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/util"
 	pb "github.com/sefikcan/ms-grpc-sample/proto"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -23,9 +36,11 @@ import (
 )
 
 type Server struct {
-	echo   *echo.Echo
-	cfg    *config.Config
-	logger logger.Logger
+	echo *echo.Echo
+	cfg  *config.Config
+	// remoteConfig is the config server document cfg was read from in REMOTE mode
+	remoteConfig []byte
+	logger       logger.Logger
 }
 
 func (s *Server) Run() error {
@@ -36,40 +51,130 @@ func (s *Server) Run() error {
 		MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
 	}
 
+	tracerProvider, err := tracing.NewTracerProvider(s.cfg, s.logger)
+	if err != nil {
+		s.logger.Fatalf("Failed to initialize tracing: %v\n", err)
+	}
+
+	if s.cfg.Server.SSL {
+		serverTLS, err := tlsconfig.NewReloader(s.cfg.Server.TLS, s.logger)
+		if err != nil {
+			s.logger.Fatalf("Failed to load server TLS certificates: %v\n", err)
+		}
+		defer serverTLS.Close()
+
+		server.TLSConfig = serverTLS.ServerConfig(http2.NextProtoTLS, "http/1.1")
+	}
+
 	go func() {
 		s.logger.Infof("Server is listening on PORT: %s", s.cfg.Server.Port)
-		if err := s.echo.StartServer(server); err != nil {
-			s.logger.Fatalf("Error starting server: ", err)
+		if err := s.echo.StartServer(server); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Fatalf("Error starting server: %v", err)
 		}
 	}()
 
-	conn, err := grpc.Dial(s.cfg.ClientsConfig.ProductServiceClientUrl, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	transportCredentials := insecure.NewCredentials()
+	if s.cfg.ClientsConfig.ProductServiceTLS.Enabled {
+		clientTLS, err := tlsconfig.NewReloader(s.cfg.ClientsConfig.ProductServiceTLS, s.logger)
+		if err != nil {
+			s.logger.Fatalf("Failed to load product service TLS certificates: %v\n", err)
+		}
+		defer clientTLS.Close()
+
+		transportCredentials = credentials.NewTLS(clientTLS.ClientConfig())
+	}
+
+	serviceConfig, err := productServiceConfig(s.cfg)
+	if err != nil {
+		s.logger.Fatalf("Invalid product service client config: %v\n", err)
+	}
+
+	interceptorManager := interceptors.NewInterceptorManager(s.cfg, s.logger)
+	circuitBreaker, err := interceptorManager.CircuitBreakerUnaryInterceptor()
+	if err != nil {
+		s.logger.Fatalf("Failed to create circuit breaker: %v\n", err)
+	}
+
+	// the timeout bounds every attempt together, retries configured in the service config happen below the interceptors
+	target, dialOpts := productServiceTarget(s.cfg)
+	dialOpts = append(dialOpts,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(
+			interceptorManager.TimeoutUnaryInterceptor(),
+			circuitBreaker,
+			interceptorManager.HedgingUnaryInterceptor(),
+		),
+	)
+	conn, err := grpc.Dial(target, dialOpts...)
 	if err != nil {
 		s.logger.Fatalf("Failed to connect: %v\n", err)
 	}
-	defer func(conn *grpc.ClientConn) {
-		err := conn.Close()
-		if err != nil {
-			s.logger.Fatal(err)
-		}
-	}(conn)
 
-	metrics, err := metric.CreateMetric(s.cfg.Metric.Url, s.cfg.Metric.ServiceName)
+	if err := metric.WatchConnectionState(context.Background(), conn, s.cfg.Metric.ServiceName, target); err != nil {
+		s.logger.Errorf("WatchConnectionState error: %s", err)
+	}
+
+	metrics, err := metric.CreateMetric(s.cfg.Metric.ServiceName)
 	if err != nil {
 		s.logger.Errorf("CreateMetric error: %s", err)
 	}
-	s.logger.Infof("Metrics available URL: %s, ServiceName: %s", s.cfg.Metric.Url, s.cfg.Metric.ServiceName)
+
+	metricServer := metric.NewServer(s.cfg.Metric.Url)
+	go func() {
+		s.logger.Infof("Metrics available URL: %s, ServiceName: %s", s.cfg.Metric.Url, s.cfg.Metric.ServiceName)
+		if err := metricServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("Error starting metric server: %v", err)
+		}
+	}()
 
 	productServiceClient := pb.NewProductServiceClient(conn)
 
 	productHandler := handlers.NewProductHandler(s.cfg, s.logger, productServiceClient)
+	healthHandler := healthHandlers.NewHealthHandler(s.cfg, s.logger, conn)
 
 	middlewareManager := middlewares.NewMiddlewareManager(s.cfg, s.logger)
+
+	var verifier *auth.Verifier
+	if s.cfg.Auth.Enabled {
+		verifier, err = auth.NewVerifier(s.cfg.Auth)
+		if err != nil {
+			s.logger.Fatalf("Failed to initialize auth: %v\n", err)
+		}
+	}
+
+	configWatcher := config.NewWatcher(s.cfg, s.remoteConfig, s.logger)
+
+	var adminServer *http.Server
+	if s.cfg.Admin.Enabled {
+		adminServer, err = admin.NewServer(s.cfg, configWatcher.Current, s.logger, verifier)
+		if err != nil {
+			s.logger.Fatalf("Failed to create admin server: %v\n", err)
+		}
+
+		go func() {
+			s.logger.Infof("Admin server is listening on %s", adminServer.Addr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Errorf("Error starting admin server: %v", err)
+			}
+		}()
+	}
+
+	s.echo.IPExtractor = ipExtractor(s.cfg.Server.TrustedProxies)
+	s.echo.Use(otelecho.Middleware(s.cfg.Jaeger.ServiceName))
 	s.echo.Use(middlewareManager.RequestLoggerMiddleware)
 
 	s.echo.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXRequestID},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXRequestID, echo.HeaderAuthorization, util.HeaderXTenantID, util.HeaderConsistencyToken},
+		ExposeHeaders: []string{
+			util.HeaderConsistencyToken,
+			middlewares.HeaderRateLimitLimit,
+			middlewares.HeaderRateLimitRemaining,
+			middlewares.HeaderRateLimitReset,
+			middlewares.HeaderRetryAfter,
+		},
 	}))
 	s.echo.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
 		StackSize:         1 << 10, //1kb
@@ -77,6 +182,7 @@ func (s *Server) Run() error {
 		DisableStackAll:   true,
 	}))
 	s.echo.Use(middleware.RequestID())
+	s.echo.Use(middlewareManager.LoggerMiddleware)
 	s.echo.Use(middlewareManager.MetricMiddleware(metrics))
 	s.echo.Use(middleware.Secure())
 	s.echo.Use(middleware.BodyLimit("2M"))
@@ -86,27 +192,122 @@ func (s *Server) Run() error {
 	health := v1.Group("/health")
 	productGroup := v1.Group("/products")
 
-	handlers.MapProductRoutes(productGroup, productHandler)
+	// one IP limiter for the product routes and the gateway, so a client IP has a single budget across both
+	ipRateLimiter := middlewareManager.NewIPRateLimiter()
+	productGroup.Use(middlewareManager.IPRateLimitMiddleware(ipRateLimiter))
+	productGroup.Use(middlewareManager.AuthMiddleware(verifier))
+	productRateLimiter := middlewareManager.NewRateLimiter()
+	productGroup.Use(middlewareManager.RateLimitMiddleware(productRateLimiter, middlewares.RegisteredRoute))
+	handlers.MapProductRoutes(productGroup, productHandler, middlewareManager)
+	healthHandlers.MapHealthRoutes(health, healthHandler)
 
-	health.GET("", func(c echo.Context) error {
-		s.logger.Infof("Health check RequestID: %s", util.GetRequestId(c))
-		return c.JSON(http.StatusOK, map[string]string{"status": "OK"})
+	configWatcher.Subscribe("logger", func(cfg *config.Config) {
+		if cfg.Logger.Level == "" {
+			return
+		}
+		if err := s.logger.SetLevel(cfg.Logger.Level); err != nil {
+			s.logger.Errorf("Failed to apply log level: %v", err)
+		}
+	})
+	configWatcher.Subscribe("rateLimit", func(cfg *config.Config) {
+		productRateLimiter.Update(middlewares.RateLimitRules(cfg.RateLimit))
+		ipRateLimiter.Update(middlewares.IPRateLimitRules(cfg.RateLimit))
 	})
+	configWatcher.Subscribe("server", interceptorManager.Reload)
+	configWatcher.Subscribe("clients", interceptorManager.Reload)
+
+	if s.cfg.Gateway.Enabled {
+		productGateway, err := gateway.NewGateway(context.Background(), s.cfg, s.logger, conn)
+		if err != nil {
+			s.logger.Fatalf("Failed to create gateway: %v\n", err)
+		}
+
+		s.echo.GET(s.cfg.Gateway.Prefix+"/openapi.json", productGateway.OpenAPI())
 
-	// gracefull shutdown
+		gatewayGroup := s.echo.Group(s.cfg.Gateway.Prefix)
+		gatewayGroup.Use(middlewareManager.IPRateLimitMiddleware(ipRateLimiter))
+		gatewayGroup.Use(middlewareManager.AuthMiddleware(verifier))
+		gatewayRateLimiter := middlewareManager.NewRateLimiter()
+		// every gateway endpoint is served by the one wildcard route
+		gatewayGroup.Use(middlewareManager.RateLimitMiddleware(gatewayRateLimiter, middlewares.RequestPath))
+		configWatcher.Subscribe("rateLimit", func(cfg *config.Config) {
+			gatewayRateLimiter.Update(middlewares.RateLimitRules(cfg.RateLimit))
+		})
+		gateway.MapGatewayRoutes(gatewayGroup, productGateway, middlewareManager)
+	}
+
+	// sections read once at startup, such as the listen address or the TLS files, still need a restart
+	if err := configWatcher.Start(); err != nil {
+		s.logger.Errorf("Failed to watch config: %v", err)
+	}
+
+	// graceful shutdown, readiness fails during the drain period so new traffic moves away
+	// before the listener closes, the product service connection outlives the in-flight requests.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-	ctx, shutdown := context.WithTimeout(context.Background(), time.Duration(s.cfg.Server.CtxTimeout)*time.Second)
+	sig := <-quit
+	s.logger.Infof("Received %s, shutting down", sig)
+	configWatcher.Stop()
+
+	healthHandler.Shutdown()
+	time.Sleep(time.Duration(s.cfg.Server.DrainPeriod) * time.Second)
+
+	shutdownTimeout := time.Duration(s.cfg.Server.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = time.Duration(s.cfg.Server.CtxTimeout) * time.Second
+	}
+	ctx, shutdown := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer shutdown()
+
+	err = s.echo.Shutdown(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to shutdown server: %v", err)
+	}
+
+	if err := metricServer.Shutdown(ctx); err != nil {
+		s.logger.Errorf("Failed to shutdown metric server: %v", err)
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			s.logger.Errorf("Failed to shutdown admin server: %v", err)
+		}
+	}
+
+	if err := conn.Close(); err != nil {
+		s.logger.Errorf("Failed to close product service connection: %v", err)
+	}
+
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		s.logger.Errorf("Failed to flush traces: %v", err)
+	}
+
 	s.logger.Info("Server exited properly")
-	return s.echo.Server.Shutdown(ctx)
+	_ = s.logger.Sync()
+	return err
+}
+
+// ipExtractor only reads X-Forwarded-For behind trusted proxies, elsewhere clients could pick their own address
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		if _, ipNet, err := net.ParseCIDR(proxy); err == nil {
+			options = append(options, echo.TrustIPRange(ipNet))
+		}
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
 }
 
-func NewServer(cfg *config.Config, logger logger.Logger) *Server {
+func NewServer(cfg *config.Config, remoteConfig []byte, logger logger.Logger) *Server {
 	return &Server{
-		echo:   echo.New(),
-		cfg:    cfg,
-		logger: logger,
+		echo:         echo.New(),
+		cfg:          cfg,
+		remoteConfig: remoteConfig,
+		logger:       logger,
 	}
 }
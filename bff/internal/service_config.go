@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/balancer/roundrobin"
+	_ "google.golang.org/grpc/health"
+	"strings"
+)
+
+const (
+	defaultRetryMaxAttempts       = 3
+	defaultRetryInitialBackoff    = 100
+	defaultRetryMaxBackoff        = 1000
+	defaultRetryBackoffMultiplier = 2
+)
+
+const pickFirst = "pick_first"
+
+// loadBalancingPolicies maps the configured policy to its registered balancer name
+var loadBalancingPolicies = map[string]string{
+	pickFirst:       pickFirst,
+	"round_robin":   roundrobin.Name,
+	"least_request": leastrequest.Name,
+}
+
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]interface{} `json:"loadBalancingConfig,omitempty"`
+	HealthCheckConfig   *healthCheckConfig       `json:"healthCheckConfig,omitempty"`
+	MethodConfig        []methodConfig           `json:"methodConfig,omitempty"`
+}
+
+type healthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type leastRequestConfig struct {
+	ChoiceCount int `json:"choiceCount,omitempty"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// productServiceConfig builds the gRPC service config of the product service connection from ClientsConfig
+func productServiceConfig(cfg *config.Config) (string, error) {
+	var sc serviceConfig
+
+	lb := cfg.ClientsConfig.ProductServiceLoadBalancing
+	if lb.Policy != "" {
+		policy, exist := loadBalancingPolicies[lb.Policy]
+		if !exist {
+			return "", fmt.Errorf("unknown load balancing policy %q", lb.Policy)
+		}
+
+		var policyConfig interface{} = struct{}{}
+		if policy == leastrequest.Name {
+			policyConfig = leastRequestConfig{ChoiceCount: lb.ChoiceCount}
+		}
+		sc.LoadBalancingConfig = []map[string]interface{}{{policy: policyConfig}}
+	}
+
+	if lb.HealthCheck {
+		if lb.Policy == "" || lb.Policy == pickFirst {
+			return "", fmt.Errorf("health checking needs the round_robin or least_request policy")
+		}
+		sc.HealthCheckConfig = &healthCheckConfig{ServiceName: pb.ProductService_ServiceDesc.ServiceName}
+	}
+
+	retry := cfg.ClientsConfig.ProductServiceRetry
+	if retry.Enabled && len(retry.Methods) > 0 {
+		names := make([]methodName, 0, len(retry.Methods))
+		for _, fullMethod := range retry.Methods {
+			service, method, found := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+			if !found {
+				return "", fmt.Errorf("retry method %q is not in /service/method form", fullMethod)
+			}
+			names = append(names, methodName{Service: service, Method: method})
+		}
+
+		policy := &retryPolicy{
+			MaxAttempts:          retry.MaxAttempts,
+			InitialBackoff:       millisecondsToDuration(retry.InitialBackoff, defaultRetryInitialBackoff),
+			MaxBackoff:           millisecondsToDuration(retry.MaxBackoff, defaultRetryMaxBackoff),
+			BackoffMultiplier:    retry.BackoffMultiplier,
+			RetryableStatusCodes: retry.RetryableStatusCodes,
+		}
+		if policy.MaxAttempts < 2 {
+			policy.MaxAttempts = defaultRetryMaxAttempts
+		}
+		if policy.BackoffMultiplier <= 0 {
+			policy.BackoffMultiplier = defaultRetryBackoffMultiplier
+		}
+		if len(policy.RetryableStatusCodes) == 0 {
+			policy.RetryableStatusCodes = []string{"UNAVAILABLE"}
+		}
+
+		sc.MethodConfig = append(sc.MethodConfig, methodConfig{Name: names, RetryPolicy: policy})
+	}
+
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// millisecondsToDuration formats milliseconds the way service config durations are written, e.g. 0.1s
+func millisecondsToDuration(ms, defaultMs int) string {
+	if ms <= 0 {
+		ms = defaultMs
+	}
+
+	return fmt.Sprintf("%.3fs", float64(ms)/1000)
+}
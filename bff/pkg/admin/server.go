@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"strings"
+)
+
+const bearerPrefix = "Bearer "
+
+var ErrUnprotected = errors.New("admin server needs a token or auth to be enabled")
+
+type server struct {
+	cfg      *config.Config
+	current  func() *config.Config
+	logger   logger.Logger
+	verifier *auth.Verifier
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+}
+
+type buildInfo struct {
+	AppVersion   string `json:"appVersion"`
+	GoVersion    string `json:"goVersion,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revisionTime,omitempty"`
+	Modified     bool   `json:"modified"`
+}
+
+// authorize accepts the configured token or, when auth is enabled, a token with the admin role
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+
+		token := s.cfg.Admin.Token
+		if token != "" && subtle.ConstantTimeCompare([]byte(authorization), []byte(bearerPrefix+token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.verifier != nil {
+			principal, err := s.verifier.VerifyAuthorization(authorization)
+			if err == nil && principal.HasRole(auth.RoleAdmin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err == nil {
+				s.logger.Warnf("Admin request forbidden, Subject: %s, Path: %s", principal.Subject, r.URL.Path)
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin role is required"})
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
+	})
+}
+
+func (s *server) level(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, levelRequest{Level: s.logger.Level()})
+	case http.MethodPut:
+		var req levelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		previous := s.logger.Level()
+		if err := s.logger.SetLevel(strings.ToLower(req.Level)); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s.logger.Warnf("Log level changed from %s to %s", previous, s.logger.Level())
+
+		writeJSON(w, http.StatusOK, levelRequest{Level: s.logger.Level()})
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *server) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	info := buildInfo{AppVersion: s.cfg.Server.AppVersion}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = build.GoVersion
+		info.Path = build.Main.Path
+		info.Version = build.Main.Version
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.RevisionTime = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *server) config(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, config.Redacted(s.current()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// NewServer serves the log level, pprof, build info and the redacted config on cfg.Admin.Host:Port,
+// current returns the config in effect so reloaded values show up on /config.
+func NewServer(cfg *config.Config, current func() *config.Config, logger logger.Logger, verifier *auth.Verifier) (*http.Server, error) {
+	if cfg.Admin.Token == "" && verifier == nil {
+		return nil, ErrUnprotected
+	}
+
+	s := &server{
+		cfg:      cfg,
+		current:  current,
+		logger:   logger,
+		verifier: verifier,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/log/level", s.level)
+	mux.HandleFunc("/buildinfo", s.buildInfo)
+	mux.HandleFunc("/config", s.config)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", cfg.Admin.Host, cfg.Admin.Port),
+		Handler: s.authorize(mux),
+	}, nil
+}
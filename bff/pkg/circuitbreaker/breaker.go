@@ -0,0 +1,166 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 10 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Outcome of a call let through by the breaker, Ignored calls release their slot without being counted
+type Outcome int
+
+const (
+	Success Outcome = iota
+	Failure
+	Ignored
+)
+
+// Settings open the breaker after FailureThreshold consecutive failures, after OpenTimeout HalfOpenRequests
+// probes are let through and the breaker closes once all of them succeeded.
+type Settings struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenRequests int
+	OnStateChange    func(from, to State)
+}
+
+type Breaker struct {
+	settings Settings
+
+	mutex      sync.Mutex
+	state      State
+	generation uint64
+	failures   int
+	probes     int
+	successes  int
+	openedAt   time.Time
+}
+
+// Allow reports whether a call may proceed, the returned done has to be called with the outcome of the call
+func (b *Breaker) Allow() (func(Outcome), error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	return func(outcome Outcome) {
+		b.done(generation, outcome)
+	}, nil
+}
+
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+// done ignores outcomes of calls started before the last state change
+func (b *Breaker) done(generation uint64, outcome Outcome) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case Closed:
+		switch outcome {
+		case Success:
+			b.failures = 0
+		case Failure:
+			b.failures++
+			if b.failures >= b.settings.FailureThreshold {
+				b.setState(Open)
+			}
+		}
+	case HalfOpen:
+		switch outcome {
+		case Success:
+			b.successes++
+			if b.successes >= b.settings.HalfOpenRequests {
+				b.setState(Closed)
+			}
+		case Failure:
+			b.setState(Open)
+		case Ignored:
+			b.probes--
+		}
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+
+	if b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, state)
+	}
+}
+
+func NewBreaker(settings Settings) *Breaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = defaultOpenTimeout
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &Breaker{
+		settings: settings,
+	}
+}
@@ -0,0 +1,173 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// openBreaker trips a breaker and moves its open timestamp back so the next Allow starts the half-open state
+func openBreaker(t *testing.T, halfOpenRequests int) *Breaker {
+	t.Helper()
+
+	b := NewBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenRequests: halfOpenRequests})
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow on a closed breaker: %v", err)
+	}
+	done(Failure)
+	if b.State() != Open {
+		t.Fatalf("state after the threshold = %s, want open", b.State())
+	}
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	return b
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		want     State
+	}{
+		{name: "below threshold", outcomes: []Outcome{Failure, Failure}, want: Closed},
+		{name: "threshold reached", outcomes: []Outcome{Failure, Failure, Failure}, want: Open},
+		{name: "success resets failures", outcomes: []Outcome{Failure, Failure, Success, Failure, Failure}, want: Closed},
+		{name: "ignored keeps failures", outcomes: []Outcome{Failure, Failure, Ignored, Failure}, want: Open},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(Settings{FailureThreshold: 3, OpenTimeout: time.Minute})
+			for _, outcome := range tt.outcomes {
+				done, err := b.Allow()
+				if err != nil {
+					t.Fatalf("Allow: %v", err)
+				}
+				done(outcome)
+			}
+
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerRejectsWhileOpen(t *testing.T) {
+	b := NewBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute})
+	done, _ := b.Allow()
+	done(Failure)
+
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow before the open timeout = %v, want %v", err, ErrOpen)
+	}
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	tests := []struct {
+		name     string
+		probes   int
+		outcomes []Outcome
+		want     State
+	}{
+		{name: "all probes succeed", probes: 2, outcomes: []Outcome{Success, Success}, want: Closed},
+		{name: "one probe fails", probes: 2, outcomes: []Outcome{Success, Failure}, want: Open},
+		{name: "probes pending", probes: 2, outcomes: []Outcome{Success}, want: HalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := openBreaker(t, tt.probes)
+
+			dones := make([]func(Outcome), 0, tt.probes)
+			for i := 0; i < tt.probes; i++ {
+				done, err := b.Allow()
+				if err != nil {
+					t.Fatalf("probe %d: %v", i, err)
+				}
+				dones = append(dones, done)
+			}
+			if b.State() != HalfOpen {
+				t.Fatalf("state after the open timeout = %s, want half-open", b.State())
+			}
+			if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Errorf("Allow beyond %d probes = %v, want %v", tt.probes, err, ErrOpen)
+			}
+
+			for i, outcome := range tt.outcomes {
+				dones[i](outcome)
+			}
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoredProbeReleasesItsSlot(t *testing.T) {
+	b := openBreaker(t, 1)
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	done(Ignored)
+
+	if _, err := b.Allow(); err != nil {
+		t.Errorf("Allow after an ignored probe = %v, want a new probe", err)
+	}
+}
+
+func TestBreakerDropsOutcomesOfEarlierGenerations(t *testing.T) {
+	b := NewBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenRequests: 1})
+
+	// a call started while closed finishes after the breaker went through open into half-open
+	stale, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	done, _ := b.Allow()
+	done(Failure)
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	stale(Failure)
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state after a stale failure = %s, want half-open", got)
+	}
+
+	probe(Success)
+	if got := b.State(); got != Closed {
+		t.Errorf("state after the probe succeeded = %s, want closed", got)
+	}
+
+	// the probe of the previous half-open state must not close a breaker that opened again
+	b = openBreaker(t, 1)
+	probe, _ = b.Allow()
+	b.mutex.Lock()
+	b.setState(Open)
+	b.mutex.Unlock()
+	probe(Success)
+	if got := b.State(); got != Open {
+		t.Errorf("state after a stale probe = %s, want open", got)
+	}
+}
+
+func TestNewBreakerDefaults(t *testing.T) {
+	b := NewBreaker(Settings{})
+
+	if b.settings.FailureThreshold != defaultFailureThreshold {
+		t.Errorf("FailureThreshold = %d, want %d", b.settings.FailureThreshold, defaultFailureThreshold)
+	}
+	if b.settings.OpenTimeout != defaultOpenTimeout {
+		t.Errorf("OpenTimeout = %s, want %s", b.settings.OpenTimeout, defaultOpenTimeout)
+	}
+	if b.settings.HalfOpenRequests != defaultHalfOpenRequests {
+		t.Errorf("HalfOpenRequests = %d, want %d", b.settings.HalfOpenRequests, defaultHalfOpenRequests)
+	}
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"os"
 	"reflect"
@@ -9,23 +11,88 @@ import (
 )
 
 const (
-	defaultConfigPath     = "bff/pkg/config/"
-	tagName               = "mapstructure"
-	configFileType        = "yaml"
-	defaultConfigFileName = "config-dev"
-	environmentKey        = "environment"
+	defaultConfigPath  = "bff/pkg/config/"
+	tagName            = "mapstructure"
+	configFileType     = "yaml"
+	baseConfigFileName = "config"
+	environmentKey     = "environment"
 )
 
 type Config struct {
-	Server        ServerConfig  `mapstructure:"server"`
-	ClientsConfig ClientsConfig `mapstructure:"clients"`
-	Metric        MetricConfig  `mapstructure:"metric"`
-	Logger        LoggerConfig  `mapstructure:"logger"`
-	Jaeger        JaegerConfig  `mapstructure:"jaeger"`
+	Server        ServerConfig    `mapstructure:"server"`
+	ClientsConfig ClientsConfig   `mapstructure:"clients"`
+	Metric        MetricConfig    `mapstructure:"metric"`
+	Logger        LoggerConfig    `mapstructure:"logger"`
+	Jaeger        JaegerConfig    `mapstructure:"jaeger"`
+	Auth          AuthConfig      `mapstructure:"auth"`
+	RateLimit     RateLimitConfig `mapstructure:"rateLimit"`
+	Gateway       GatewayConfig   `mapstructure:"gateway"`
+	Admin         AdminConfig     `mapstructure:"admin"`
 }
 
 type ClientsConfig struct {
-	ProductServiceClientUrl string `mapstructure:"productClientUrl"`
+	ProductServiceClientUrl string    `mapstructure:"productClientUrl"`
+	ProductServiceTLS       TLSConfig `mapstructure:"productTls"`
+	// ProductServiceEndpoints are static replica addresses used instead of ProductServiceClientUrl,
+	// which can also be a dns:/// target resolving to every replica
+	ProductServiceEndpoints     []string            `mapstructure:"productEndpoints"`
+	ProductServiceLoadBalancing LoadBalancingConfig `mapstructure:"productLoadBalancing"`
+	// ProductServiceTimeouts override Server.CtxTimeout for single product service methods
+	ProductServiceTimeouts       []MethodTimeoutConfig `mapstructure:"productTimeouts"`
+	ProductServiceRetry          RetryConfig           `mapstructure:"productRetry"`
+	ProductServiceCircuitBreaker CircuitBreakerConfig  `mapstructure:"productCircuitBreaker"`
+	ProductServiceHedging        HedgingConfig         `mapstructure:"productHedging"`
+}
+
+// RetryConfig becomes the retry policy of the gRPC service config, only idempotent methods belong in Methods
+type RetryConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Methods     []string `mapstructure:"methods"`
+	MaxAttempts int      `mapstructure:"maxAttempts"`
+	// InitialBackoff and MaxBackoff in milliseconds
+	InitialBackoff    int     `mapstructure:"initialBackoff"`
+	MaxBackoff        int     `mapstructure:"maxBackoff"`
+	BackoffMultiplier float64 `mapstructure:"backoffMultiplier"`
+	// RetryableStatusCodes are gRPC code names, e.g. UNAVAILABLE
+	RetryableStatusCodes []string `mapstructure:"retryableStatusCodes"`
+}
+
+type CircuitBreakerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// FailureThreshold consecutive failures open the breaker
+	FailureThreshold int `mapstructure:"failureThreshold"`
+	// OpenTimeout in milliseconds before half-open probes are let through
+	OpenTimeout int `mapstructure:"openTimeout"`
+	// HalfOpenRequests probes have to succeed to close the breaker again
+	HalfOpenRequests int `mapstructure:"halfOpenRequests"`
+}
+
+// HedgingConfig sends further attempts of slow reads before the first one has answered
+type HedgingConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Methods     []string `mapstructure:"methods"`
+	MaxAttempts int      `mapstructure:"maxAttempts"`
+	// Delay in milliseconds between attempts
+	Delay int `mapstructure:"delay"`
+	// NonFatalStatusCodes send the next attempt right away instead of failing the call
+	NonFatalStatusCodes []string `mapstructure:"nonFatalStatusCodes"`
+}
+
+type LoadBalancingConfig struct {
+	// Policy is pick_first, round_robin or least_request
+	Policy string `mapstructure:"policy"`
+	// ChoiceCount replicas are sampled by least_request to find the least loaded one, between 2 and 10
+	ChoiceCount int `mapstructure:"choiceCount"`
+	// HealthCheck watches each replica through the gRPC health service and skips those not serving,
+	// it needs round_robin or least_request
+	HealthCheck bool `mapstructure:"healthCheck"`
+}
+
+type MethodTimeoutConfig struct {
+	// Method is the full gRPC method name, e.g. /product.ProductService/GetProductDetail
+	Method string `mapstructure:"method"`
+	// Timeout in milliseconds
+	Timeout int `mapstructure:"timeout"`
 }
 
 type ServerConfig struct {
@@ -38,12 +105,88 @@ type ServerConfig struct {
 	SSL            bool   `mapstructure:"ssl"`
 	MaxHeaderBytes int    `mapstructure:"maxHeaderBytes"`
 	CtxTimeout     int    `mapstructure:"ctxTimeout"`
+	// DrainPeriod in seconds between failing readiness and closing the listener on shutdown
+	DrainPeriod int `mapstructure:"drainPeriod"`
+	// ShutdownTimeout in seconds to wait for in-flight requests before they are dropped
+	ShutdownTimeout int `mapstructure:"shutdownTimeout"`
+	// TLS is served when SSL is true
+	TLS TLSConfig `mapstructure:"tls"`
+	// TrustedProxies are the CIDRs whose X-Forwarded-For header is believed, the peer address is used without them
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+}
+
+type TLSConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	CertFile   string `mapstructure:"certFile"`
+	KeyFile    string `mapstructure:"keyFile"`
+	CAFile     string `mapstructure:"caFile"`
+	ServerName string `mapstructure:"serverName"`
+	// ClientAuth is used by servers: none, request, require, verifyIfGiven or requireAndVerify
+	ClientAuth string `mapstructure:"clientAuth"`
 }
 
 type LoggerConfig struct {
-	Development bool   `mapstructure:"development"`
-	Encoding    string `mapstructure:"encoding"`
-	Level       string `mapstructure:"level"`
+	Development      bool             `mapstructure:"development"`
+	Encoding         string           `mapstructure:"encoding"`
+	Level            string           `mapstructure:"level"`
+	ElasticSearchUrl string           `mapstructure:"elasticSearchUrl"`
+	Elastic          ElasticLogConfig `mapstructure:"elastic"`
+	// Sinks receive every entry at or above their own level, stderr with Level and Encoding is used when there are none
+	Sinks    []LogSinkConfig   `mapstructure:"sinks"`
+	Sampling LogSamplingConfig `mapstructure:"sampling"`
+}
+
+// LogSinkConfig is a log destination: stdout, stderr, file, elasticsearch or syslog.
+// Level and Encoding fall back to the logger ones, Encoding is json or console.
+type LogSinkConfig struct {
+	Type     string            `mapstructure:"type"`
+	Level    string            `mapstructure:"level"`
+	Encoding string            `mapstructure:"encoding"`
+	File     FileLogSinkConfig `mapstructure:"file"`
+	Syslog   SyslogSinkConfig  `mapstructure:"syslog"`
+}
+
+// FileLogSinkConfig rotates Path once it reaches MaxSize megabytes, rotated files are removed
+// after MaxAge days or when there are more than MaxBackups of them, zero keeps them.
+type FileLogSinkConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxSize    int    `mapstructure:"maxSize"`
+	MaxAge     int    `mapstructure:"maxAge"`
+	MaxBackups int    `mapstructure:"maxBackups"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// SyslogSinkConfig writes to the local syslog daemon when Network and Address are empty
+type SyslogSinkConfig struct {
+	Network  string `mapstructure:"network"`
+	Address  string `mapstructure:"address"`
+	Tag      string `mapstructure:"tag"`
+	Facility string `mapstructure:"facility"`
+}
+
+// LogSamplingConfig logs the first Initial entries with the same level and message every Tick milliseconds
+// and every Thereafter-th one after that.
+type LogSamplingConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	Tick       int  `mapstructure:"tick"`
+	Initial    int  `mapstructure:"initial"`
+	Thereafter int  `mapstructure:"thereafter"`
+}
+
+// ElasticLogConfig configures the elasticsearch sink, entries are shipped to ElasticSearchUrl
+// in bulk requests from a bounded queue
+type ElasticLogConfig struct {
+	// Index is the index or, with DataStream, the data stream the entries are written to
+	Index      string `mapstructure:"index"`
+	DataStream bool   `mapstructure:"dataStream"`
+	BatchSize  int    `mapstructure:"batchSize"`
+	// FlushInterval in milliseconds, a batch is sent when it is full or the interval elapsed
+	FlushInterval int `mapstructure:"flushInterval"`
+	QueueSize     int `mapstructure:"queueSize"`
+	// QueuePolicy is drop or block, it decides what logging does while the queue is full
+	QueuePolicy string `mapstructure:"queuePolicy"`
+	// MaxRetries of a failed bulk request before its entries are dropped, 3 when unset
+	MaxRetries int `mapstructure:"maxRetries"`
 }
 
 type MetricConfig struct {
@@ -51,13 +194,77 @@ type MetricConfig struct {
 	ServiceName string `mapstructure:"serviceName"`
 }
 
+type AuthConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	Issuer           string `mapstructure:"issuer"`
+	Audience         string `mapstructure:"audience"`
+	HMACSecret       string `mapstructure:"hmacSecret"`
+	RSAPublicKeyFile string `mapstructure:"rsaPublicKeyFile"`
+	JWKSFile         string `mapstructure:"jwksFile"`
+	// RolesClaim names the claim holding viewer, editor or admin roles, roles by default
+	RolesClaim string `mapstructure:"rolesClaim"`
+}
+
+// AdminConfig is the listener for runtime operations, keep Host off public interfaces.
+// Callers need Token as bearer token or, with auth enabled, a token with the admin role.
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    string `mapstructure:"port"`
+	Token   string `mapstructure:"token"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Rate is the requests per second each client may make and Burst how many it may make at once
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+	// ApiKeyHeader identifies clients without a JWT subject by one of ApiKeys, other values fall back to the IP address
+	ApiKeyHeader string                 `mapstructure:"apiKeyHeader"`
+	ApiKeys      []string               `mapstructure:"apiKeys"`
+	Routes       []RouteRateLimitConfig `mapstructure:"routes"`
+	IP           IPRateLimitConfig      `mapstructure:"ip"`
+}
+
+// IPRateLimitConfig limits every client IP across all routes before authentication, so floods without a valid
+// token are limited too, a Rate of 0 disables it
+type IPRateLimitConfig struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+type RouteRateLimitConfig struct {
+	Method string  `mapstructure:"method"`
+	Path   string  `mapstructure:"path"`
+	Rate   float64 `mapstructure:"rate"`
+	Burst  int     `mapstructure:"burst"`
+}
+
+// GatewayConfig serves the REST routes generated from the proto annotations under Prefix
+type GatewayConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Prefix  string `mapstructure:"prefix"`
+}
+
+// JaegerConfig configures tracing, spans go to the Jaeger agent on Host or to an OTLP collector
 type JaegerConfig struct {
 	Host        string `mapstructure:"host"`
 	ServiceName string `mapstructure:"serviceName"`
-	LogSpans    bool   `mapstructure:"logSpans"`
+	// LogSpans logs every finished span at debug level
+	LogSpans bool `mapstructure:"logSpans"`
+	// Exporter is jaeger, otlp or none
+	Exporter string `mapstructure:"exporter"`
+	// OtlpEndpoint is the host:port of the OTLP gRPC collector, OtlpInsecure disables TLS towards it
+	OtlpEndpoint string `mapstructure:"otlpEndpoint"`
+	OtlpInsecure bool   `mapstructure:"otlpInsecure"`
+	// Sampler is always, never or ratio, the decision of the caller is kept when there is one
+	Sampler      string  `mapstructure:"sampler"`
+	SamplerRatio float64 `mapstructure:"samplerRatio"`
 }
 
-func NewConfig() *Config {
+// NewConfig reads the config of the environment, it terminates listing every problem when the config is unusable.
+// In REMOTE mode the config server document it was read from is returned as well, the Watcher starts from it.
+func NewConfig() (*Config, []byte) {
 	env, _ := os.LookupEnv(environmentKey)
 	fmt.Println("Environment: [" + env + "] read from runtime arguments [" + environmentKey + "].")
 
@@ -90,45 +297,31 @@ func getAllKeys(t reflect.Type) []string {
 	return result
 }
 
-var readFromEnv = func(v *viper.Viper) *viper.Viper {
-	fmt.Println("Reading environment configuration")
-	addKeysToViper(v)
-	v.AutomaticEnv()
-	return v
-}
-
-var readFromConfigServer = func(v *viper.Viper) *viper.Viper {
-	//TODO: Implement config server
-	return v
-}
-
-var readFromAppYml = func(v *viper.Viper) *viper.Viper {
-	fmt.Println("Reading application yml configuration")
-	v.SetConfigName(defaultConfigFileName)
-	v.SetTypeByDefaultValue(true)
-	v.SetConfigType(configFileType)
-	v.AddConfigPath("./" + defaultConfigPath)
-	if err := v.ReadInConfig(); err != nil {
-		fmt.Printf("Viper read config has an error; %e\n", err)
+var ReadConfig = func(c *Config, env string) (*Config, []byte) {
+	fmt.Println("Configuration read initiated...")
+	var remote []byte
+	l, err := newLoader(env, os.Args[1:])
+	if err == nil {
+		remote, err = l.load(c, nil)
+	}
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		exitWithProblems(err)
 	}
 
-	return v
+	return c, remote
 }
 
-var ReadConfig = func(c *Config, env string) *Config {
-	fmt.Println("Configuration read initiated...")
-	v := viper.New()
-	switch {
-	case env == "DEV":
-		v = readFromAppYml(v)
-	case env == "REMOTE":
-		v = readFromConfigServer(v)
-	default:
-		v = readFromEnv(v)
+// exitWithProblems prints every problem on its own line, a panic would bury them under a stack trace
+func exitWithProblems(err error) {
+	fmt.Fprintln(os.Stderr, "Configuration is invalid, terminating:")
+	for _, problem := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintln(os.Stderr, "  - "+problem)
 	}
-	if err := v.Unmarshal(&c); err != nil {
-		panic(any("Configuration unmarshalling occurred an error, terminating: " + err.Error()))
-	}
-
-	return c
+	os.Exit(1)
 }
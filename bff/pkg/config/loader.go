@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const configDirFlag = "config-dir"
+
+// loader reads the config layers, each overriding the ones before it: defaults, config.yaml,
+// config-{environment}.yaml or the config server document in REMOTE mode, environment variables named
+// like SERVER.PORT and command line flags named like --server.port.
+type loader struct {
+	env   string
+	dir   string
+	flags *pflag.FlagSet
+}
+
+func newLoader(env string, args []string) (*loader, error) {
+	flags := newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	dir, _ := flags.GetString(configDirFlag)
+
+	return &loader{env: env, dir: dir, flags: flags}, nil
+}
+
+// newFlagSet has a flag for every key except lists of sections, other lists take comma separated values
+func newFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet(defaultApplicationName, pflag.ContinueOnError)
+	flags.String(configDirFlag, defaultConfigPath, "directory of config.yaml and config-{environment}.yaml")
+	for _, key := range flagKeys(reflect.TypeOf(Config{}), "") {
+		flags.String(key, "", "overrides "+key)
+	}
+
+	return flags
+}
+
+func flagKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + f.Tag.Get(tagName)
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			keys = append(keys, flagKeys(f.Type, key+".")...)
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+		default:
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// files are the yaml layers, the environment file is required when an environment other than REMOTE is named
+func (l *loader) files() (base string, env string) {
+	base = filepath.Join(l.dir, baseConfigFileName+"."+configFileType)
+	if l.env != "" && l.env != "REMOTE" {
+		env = filepath.Join(l.dir, baseConfigFileName+"-"+strings.ToLower(l.env)+"."+configFileType)
+	}
+
+	return base, env
+}
+
+// load reads every layer into c, remote is used instead of fetching the config server document when it is set.
+// The document used is returned, all source problems are returned together and c is only decoded when there are none.
+// Values that do not fit their key are reported along with the validation problems of the values that did.
+func (l *loader) load(c *Config, remote []byte) ([]byte, error) {
+	v := viper.New()
+	v.SetConfigType(configFileType)
+	addKeysToViper(v)
+	setDefaults(v)
+
+	var errs []error
+	base, env := l.files()
+	if err := mergeFile(v, base, false); err != nil {
+		errs = append(errs, err)
+	}
+	if env != "" {
+		if err := mergeFile(v, env, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if l.env == "REMOTE" {
+		if remote == nil {
+			var err error
+			if remote, err = fetchRemote(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if remote != nil {
+			if err := v.MergeConfig(bytes.NewReader(remote)); err != nil {
+				errs = append(errs, fmt.Errorf("config server document is not valid yaml: %w", err))
+			}
+		}
+	}
+
+	v.AutomaticEnv()
+	l.flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != configDirFlag {
+			_ = v.BindPFlag(flag.Name, flag)
+		}
+	})
+
+	if len(errs) > 0 {
+		return remote, errors.Join(errs...)
+	}
+	if err := v.Unmarshal(c); err != nil {
+		// one problem per value that does not fit its key, the rest of c is decoded and validated anyway
+		var decodeErr *mapstructure.Error
+		if !errors.As(err, &decodeErr) {
+			return remote, err
+		}
+		for _, problem := range decodeErr.Errors {
+			errs = append(errs, errors.New(problem))
+		}
+		return remote, errors.Join(append(errs, c.Validate())...)
+	}
+
+	return remote, nil
+}
+
+func mergeFile(v *viper.Viper, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("config file %s does not exist", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Reading configuration file " + path)
+	if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("config file %s is not valid yaml: %w", path, err)
+	}
+
+	return nil
+}
+
+func fetchRemote() ([]byte, error) {
+	fmt.Println("Reading config server configuration")
+	rc, err := newRemoteConfig()
+	if err != nil {
+		return nil, fmt.Errorf("config server is misconfigured: %w", err)
+	}
+
+	data, err := rc.load()
+	if err != nil {
+		return nil, fmt.Errorf("config server configuration could not be read: %w", err)
+	}
+
+	return data, nil
+}
+
+// setDefaults is the lowest layer, every file, variable or flag overrides it
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server.host", "0.0.0.0")
+	v.SetDefault("server.readTimeout", 5)
+	v.SetDefault("server.writeTimeout", 5)
+	v.SetDefault("server.ctxTimeout", 5)
+	v.SetDefault("server.drainPeriod", 5)
+	v.SetDefault("server.shutdownTimeout", 30)
+	v.SetDefault("logger.encoding", "json")
+	v.SetDefault("logger.level", "info")
+	v.SetDefault("jaeger.exporter", "jaeger")
+	v.SetDefault("jaeger.sampler", "always")
+	v.SetDefault("jaeger.samplerRatio", 1)
+	v.SetDefault("auth.rolesClaim", "roles")
+	v.SetDefault("gateway.prefix", "/gateway")
+	v.SetDefault("admin.host", "127.0.0.1")
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoaderLoadReportsEveryProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		// want are parts of the reported problems, none are expected when it is empty
+		want []string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "value that does not fit its key",
+			args: []string{"--server.ctxTimeout", "ten"},
+			want: []string{"'server.ctxTimeout'"},
+		},
+		{
+			name: "decode and validation problems together",
+			args: []string{"--server.ctxTimeout", "ten", "--server.port", "0", "--logger.level", "loud"},
+			want: []string{"'server.ctxTimeout'", "server.port must be a port", "logger.level must be one of"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newLoader("", append([]string{"--" + configDirFlag, "."}, tt.args...))
+			if err != nil {
+				t.Fatalf("newLoader: %v", err)
+			}
+
+			c := &Config{}
+			// validated afterwards like ReadConfig does, load only validates when decoding failed
+			_, err = l.load(c, nil)
+			if err == nil {
+				err = c.Validate()
+			}
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("err = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+const testDocument = "server:\n  port: \"5000\"\n"
+
+// newTestRemoteConfig configures the config server from env the way the service does, without waiting between retries
+func newTestRemoteConfig(t *testing.T, env map[string]string) *remoteConfig {
+	t.Helper()
+
+	t.Setenv(configServerApplicationKey, "app")
+	t.Setenv(configServerBackoffKey, "1")
+	t.Setenv(configCacheFileKey, filepath.Join(t.TempDir(), "cache.yaml"))
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	rc, err := newRemoteConfig()
+	if err != nil {
+		t.Fatalf("newRemoteConfig: %v", err)
+	}
+
+	return rc
+}
+
+func writeJSONResponse(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+// etcdKV answers a range request for key with value, any other key has no kvs
+func etcdKV(t *testing.T, w http.ResponseWriter, r *http.Request, key, value string) {
+	t.Helper()
+
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Errorf("decode range request: %v", err)
+	}
+
+	kvs := []map[string]string{}
+	if req.Key == base64.StdEncoding.EncodeToString([]byte(key)) {
+		kvs = append(kvs, map[string]string{"value": base64.StdEncoding.EncodeToString([]byte(value))})
+	}
+	writeJSONResponse(t, w, map[string]interface{}{"kvs": kvs})
+}
+
+func TestRemoteConfigLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		handler func(t *testing.T) http.HandlerFunc
+		want    string
+		wantErr error
+	}{
+		{
+			name: "spring with a bearer token",
+			env:  map[string]string{configServerTypeKey: ConfigServerSpring, configServerProfileKey: "prod", configServerTokenKey: "secret"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/app-prod.yml" {
+						t.Errorf("path = %s, want /app-prod.yml", r.URL.Path)
+					}
+					if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+						t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+					}
+					_, _ = w.Write([]byte(testDocument))
+				}
+			},
+			want: testDocument,
+		},
+		{
+			name: "spring with a label and basic auth",
+			env: map[string]string{
+				configServerTypeKey:     ConfigServerSpring,
+				configServerLabelKey:    "main",
+				configServerUsernameKey: "user",
+				configServerPasswordKey: "pass",
+			},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/main/app-default.yml" {
+						t.Errorf("path = %s, want /main/app-default.yml", r.URL.Path)
+					}
+					if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+						t.Errorf("basic auth = %q:%q, want user:pass", username, password)
+					}
+					_, _ = w.Write([]byte(testDocument))
+				}
+			},
+			want: testDocument,
+		},
+		{
+			name: "consul raw key",
+			env:  map[string]string{configServerTypeKey: ConfigServerConsul, configServerTokenKey: "acl"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v1/kv/config/app/default" {
+						t.Errorf("path = %s, want /v1/kv/config/app/default", r.URL.Path)
+					}
+					if _, raw := r.URL.Query()["raw"]; !raw {
+						t.Errorf("query = %s, want raw", r.URL.RawQuery)
+					}
+					if got := r.Header.Get("X-Consul-Token"); got != "acl" {
+						t.Errorf("X-Consul-Token = %q, want %q", got, "acl")
+					}
+					_, _ = w.Write([]byte(testDocument))
+				}
+			},
+			want: testDocument,
+		},
+		{
+			name: "etcd with a token",
+			env:  map[string]string{configServerTypeKey: ConfigServerEtcd, configServerKeyKey: "/services/app", configServerTokenKey: "etcd-token"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v3/kv/range" {
+						t.Errorf("path = %s, want /v3/kv/range", r.URL.Path)
+					}
+					if got := r.Header.Get("Authorization"); got != "etcd-token" {
+						t.Errorf("Authorization = %q, want %q", got, "etcd-token")
+					}
+					etcdKV(t, w, r, "/services/app", testDocument)
+				}
+			},
+			want: testDocument,
+		},
+		{
+			name: "etcd authenticates users before the range",
+			env:  map[string]string{configServerTypeKey: ConfigServerEtcd, configServerUsernameKey: "root", configServerPasswordKey: "pass"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v3/auth/authenticate":
+						var req map[string]string
+						if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+							t.Errorf("decode authenticate request: %v", err)
+						}
+						if req["name"] != "root" || req["password"] != "pass" {
+							t.Errorf("authenticate = %v, want root:pass", req)
+						}
+						writeJSONResponse(t, w, map[string]string{"token": "issued"})
+					case "/v3/kv/range":
+						if got := r.Header.Get("Authorization"); got != "issued" {
+							t.Errorf("Authorization = %q, want the issued token", got)
+						}
+						etcdKV(t, w, r, "config/app/default", testDocument)
+					default:
+						t.Errorf("unexpected path %s", r.URL.Path)
+						w.WriteHeader(http.StatusBadRequest)
+					}
+				}
+			},
+			want: testDocument,
+		},
+		{
+			name: "etcd key without kvs",
+			env:  map[string]string{configServerTypeKey: ConfigServerEtcd, configServerKeyKey: "missing"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					etcdKV(t, w, r, "config/app/default", testDocument)
+				}
+			},
+			wantErr: errConfigNotFound,
+		},
+		{
+			name: "etcd rejects the credentials",
+			env:  map[string]string{configServerTypeKey: ConfigServerEtcd, configServerUsernameKey: "root", configServerPasswordKey: "wrong"},
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			},
+			wantErr: errConfigUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler(t))
+			defer server.Close()
+
+			env := map[string]string{configServerUrlKey: server.URL}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			rc := newTestRemoteConfig(t, env)
+
+			got, err := rc.load()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("document = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "app"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "app", "default"), []byte(testDocument), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr error
+	}{
+		{name: "default key", want: testDocument},
+		{name: "missing key", key: "config/app/prod", wantErr: errConfigNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{configServerTypeKey: ConfigServerFile, configServerUrlKey: "file://" + dir}
+			if tt.key != "" {
+				env[configServerKeyKey] = tt.key
+			}
+			rc := newTestRemoteConfig(t, env)
+
+			got, err := rc.load()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("document = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigLoadRetries(t *testing.T) {
+	const retries = 2
+
+	tests := []struct {
+		name         string
+		status       int
+		cached       bool
+		wantAttempts int32
+		want         string
+		wantErr      error
+		// wantFailure expects an error without a sentinel to match, the server and the cache both failed
+		wantFailure bool
+	}{
+		{name: "not found is final", status: http.StatusNotFound, cached: true, wantAttempts: 1, wantErr: errConfigNotFound},
+		{name: "unauthorized is final", status: http.StatusUnauthorized, cached: true, wantAttempts: 1, wantErr: errConfigUnauthorized},
+		{name: "forbidden is final", status: http.StatusForbidden, cached: true, wantAttempts: 1, wantErr: errConfigUnauthorized},
+		{name: "server error falls back to the cache", status: http.StatusInternalServerError, cached: true, wantAttempts: retries + 1, want: "cached: true\n"},
+		{name: "server error without a cache", status: http.StatusInternalServerError, wantAttempts: retries + 1, wantFailure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			rc := newTestRemoteConfig(t, map[string]string{configServerUrlKey: server.URL, configServerRetriesKey: "2"})
+			if tt.cached {
+				if err := os.WriteFile(rc.CacheFile, []byte("cached: true\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := rc.load()
+			if tt.wantFailure {
+				if err == nil {
+					t.Fatal("err = nil, want an unreachable config server")
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("document = %q, want %q", got, tt.want)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigLoadCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testDocument))
+	}))
+	rc := newTestRemoteConfig(t, map[string]string{configServerUrlKey: server.URL, configServerRetriesKey: "1"})
+
+	if _, err := rc.load(); err != nil {
+		t.Fatalf("load from the server: %v", err)
+	}
+	cached, err := os.ReadFile(rc.CacheFile)
+	if err != nil {
+		t.Fatalf("read cache: %v", err)
+	}
+	if string(cached) != testDocument {
+		t.Errorf("cache = %q, want %q", cached, testDocument)
+	}
+
+	// the same config server, now unreachable
+	server.Close()
+
+	got, err := rc.load()
+	if err != nil {
+		t.Fatalf("load from the cache: %v", err)
+	}
+	if string(got) != testDocument {
+		t.Errorf("document = %q, want the cached %q", got, testDocument)
+	}
+}
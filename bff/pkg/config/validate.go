@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var logLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// problems collects validation errors with the key they belong to, so all of them are reported at once
+type problems []error
+
+func (p *problems) add(key, format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) required(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+	}
+}
+
+func (p *problems) port(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+		return
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		p.add(key, "must be a port between 1 and 65535, got %q", value)
+	}
+}
+
+func (p *problems) url(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+		return
+	}
+	if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+		p.add(key, "must be an absolute url, got %q", value)
+	}
+}
+
+func (p *problems) nonNegative(key string, value float64) {
+	if value < 0 {
+		p.add(key, "must not be negative, got %v", value)
+	}
+}
+
+func (p *problems) between(key string, value, min, max float64) {
+	if value < min || value > max {
+		p.add(key, "must be between %v and %v, got %v", min, max, value)
+	}
+}
+
+// rateRule rejects negative values and a zero burst next to a positive rate, such a bucket never holds a token
+func (p *problems) rateRule(prefix string, rate float64, burst int) {
+	p.nonNegative(prefix+"rate", rate)
+	p.nonNegative(prefix+"burst", float64(burst))
+	if rate > 0 && burst < 1 {
+		p.add(prefix+"burst", "must be at least 1 when %srate is set, got %d", prefix, burst)
+	}
+}
+
+// oneOf accepts an empty value, the code falls back to a default for those
+func (p *problems) oneOf(key, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	p.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+// Validate reports every missing or out of range value at once, one per line
+func (c *Config) Validate() error {
+	var p problems
+
+	p.port("server.port", c.Server.Port)
+	p.nonNegative("server.readTimeout", float64(c.Server.ReadTimeout))
+	p.nonNegative("server.writeTimeout", float64(c.Server.WriteTimeout))
+	p.nonNegative("server.maxHeaderBytes", float64(c.Server.MaxHeaderBytes))
+	p.nonNegative("server.ctxTimeout", float64(c.Server.CtxTimeout))
+	p.nonNegative("server.drainPeriod", float64(c.Server.DrainPeriod))
+	p.nonNegative("server.shutdownTimeout", float64(c.Server.ShutdownTimeout))
+	for i, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			p.add(fmt.Sprintf("server.trustedProxies[%d]", i), "must be a CIDR such as 10.0.0.0/8, got %q", proxy)
+		}
+	}
+	if c.Server.SSL {
+		p.required("server.tls.certFile", c.Server.TLS.CertFile)
+		p.required("server.tls.keyFile", c.Server.TLS.KeyFile)
+	}
+
+	clients := c.ClientsConfig
+	if len(clients.ProductServiceEndpoints) == 0 {
+		p.required("clients.productClientUrl", clients.ProductServiceClientUrl)
+	}
+	if clients.ProductServiceTLS.Enabled {
+		p.required("clients.productTls.caFile", clients.ProductServiceTLS.CAFile)
+	}
+	lb := clients.ProductServiceLoadBalancing
+	p.oneOf("clients.productLoadBalancing.policy", lb.Policy, "pick_first", "round_robin", "least_request")
+	if lb.Policy == "least_request" && lb.ChoiceCount != 0 {
+		p.between("clients.productLoadBalancing.choiceCount", float64(lb.ChoiceCount), 2, 10)
+	}
+	if lb.HealthCheck && (lb.Policy == "" || lb.Policy == "pick_first") {
+		p.add("clients.productLoadBalancing.healthCheck", "needs the round_robin or least_request policy")
+	}
+	for i, method := range clients.ProductServiceTimeouts {
+		key := fmt.Sprintf("clients.productTimeouts[%d]", i)
+		p.required(key+".method", method.Method)
+		p.nonNegative(key+".timeout", float64(method.Timeout))
+	}
+	if retry := clients.ProductServiceRetry; retry.Enabled {
+		for i, method := range retry.Methods {
+			if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+				p.add(fmt.Sprintf("clients.productRetry.methods[%d]", i), "must be in /service/method form, got %q", method)
+			}
+		}
+		p.nonNegative("clients.productRetry.maxAttempts", float64(retry.MaxAttempts))
+		p.nonNegative("clients.productRetry.initialBackoff", float64(retry.InitialBackoff))
+		p.nonNegative("clients.productRetry.maxBackoff", float64(retry.MaxBackoff))
+		p.nonNegative("clients.productRetry.backoffMultiplier", retry.BackoffMultiplier)
+	}
+	if breaker := clients.ProductServiceCircuitBreaker; breaker.Enabled {
+		p.between("clients.productCircuitBreaker.failureThreshold", float64(breaker.FailureThreshold), 1, 1000)
+		p.nonNegative("clients.productCircuitBreaker.openTimeout", float64(breaker.OpenTimeout))
+		p.nonNegative("clients.productCircuitBreaker.halfOpenRequests", float64(breaker.HalfOpenRequests))
+	}
+	if hedging := clients.ProductServiceHedging; hedging.Enabled {
+		p.nonNegative("clients.productHedging.maxAttempts", float64(hedging.MaxAttempts))
+		p.nonNegative("clients.productHedging.delay", float64(hedging.Delay))
+	}
+
+	p.oneOf("logger.level", c.Logger.Level, logLevels...)
+	p.oneOf("logger.encoding", c.Logger.Encoding, "json", "console")
+	for i, sink := range c.Logger.Sinks {
+		key := fmt.Sprintf("logger.sinks[%d]", i)
+		p.required(key+".type", sink.Type)
+		p.oneOf(key+".type", sink.Type, "stdout", "stderr", "file", "elasticsearch", "syslog")
+		p.oneOf(key+".level", sink.Level, logLevels...)
+		p.oneOf(key+".encoding", sink.Encoding, "json", "console")
+		switch sink.Type {
+		case "file":
+			p.required(key+".file.path", sink.File.Path)
+			p.nonNegative(key+".file.maxSize", float64(sink.File.MaxSize))
+			p.nonNegative(key+".file.maxAge", float64(sink.File.MaxAge))
+			p.nonNegative(key+".file.maxBackups", float64(sink.File.MaxBackups))
+		case "elasticsearch":
+			p.url("logger.elasticSearchUrl", c.Logger.ElasticSearchUrl)
+		}
+	}
+	p.oneOf("logger.elastic.queuePolicy", c.Logger.Elastic.QueuePolicy, "drop", "block")
+	p.nonNegative("logger.elastic.batchSize", float64(c.Logger.Elastic.BatchSize))
+	p.nonNegative("logger.elastic.flushInterval", float64(c.Logger.Elastic.FlushInterval))
+	p.nonNegative("logger.elastic.queueSize", float64(c.Logger.Elastic.QueueSize))
+	p.nonNegative("logger.elastic.maxRetries", float64(c.Logger.Elastic.MaxRetries))
+	if c.Logger.Sampling.Enabled {
+		p.between("logger.sampling.tick", float64(c.Logger.Sampling.Tick), 1, 3600000)
+		p.nonNegative("logger.sampling.initial", float64(c.Logger.Sampling.Initial))
+		p.nonNegative("logger.sampling.thereafter", float64(c.Logger.Sampling.Thereafter))
+	}
+
+	p.oneOf("jaeger.exporter", c.Jaeger.Exporter, "jaeger", "otlp", "none")
+	switch c.Jaeger.Exporter {
+	case "", "jaeger":
+		p.required("jaeger.host", c.Jaeger.Host)
+	case "otlp":
+		p.required("jaeger.otlpEndpoint", c.Jaeger.OtlpEndpoint)
+	}
+	p.oneOf("jaeger.sampler", c.Jaeger.Sampler, "always", "never", "ratio")
+	if c.Jaeger.Sampler == "ratio" {
+		p.between("jaeger.samplerRatio", c.Jaeger.SamplerRatio, 0, 1)
+	}
+
+	p.required("metric.url", c.Metric.Url)
+
+	if c.Auth.Enabled && c.Auth.HMACSecret == "" && c.Auth.RSAPublicKeyFile == "" && c.Auth.JWKSFile == "" {
+		p.add("auth", "needs one of hmacSecret, rsaPublicKeyFile or jwksFile when enabled")
+	}
+
+	if c.RateLimit.Enabled {
+		p.rateRule("rateLimit.", c.RateLimit.Rate, c.RateLimit.Burst)
+		p.rateRule("rateLimit.ip.", c.RateLimit.IP.Rate, c.RateLimit.IP.Burst)
+		for i, key := range c.RateLimit.ApiKeys {
+			p.required(fmt.Sprintf("rateLimit.apiKeys[%d]", i), key)
+		}
+		for i, route := range c.RateLimit.Routes {
+			key := fmt.Sprintf("rateLimit.routes[%d]", i)
+			p.required(key+".method", route.Method)
+			p.required(key+".path", route.Path)
+			p.rateRule(key+".", route.Rate, route.Burst)
+		}
+	}
+
+	if c.Gateway.Enabled && !strings.HasPrefix(c.Gateway.Prefix, "/") {
+		p.add("gateway.prefix", "must start with /, got %q", c.Gateway.Prefix)
+	}
+
+	if c.Admin.Enabled {
+		p.port("admin.port", c.Admin.Port)
+		if c.Admin.Token == "" && !c.Auth.Enabled {
+			p.add("admin", "needs a token or auth enabled, it would be unprotected")
+		}
+	}
+
+	return errors.Join(p...)
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	configServerPollIntervalKey = "CONFIG_SERVER_POLL_INTERVAL"
+	defaultConfigPollInterval   = 30 * time.Second
+)
+
+// Logger is what the watcher needs from the service logger
+type Logger interface {
+	Infof(template string, args ...interface{})
+	Warnf(template string, args ...interface{})
+	Errorf(template string, args ...interface{})
+}
+
+// Change of a single config key, secrets are redacted
+type Change struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// Section is the top level key the change belongs to, e.g. logger
+func (c Change) Section() string {
+	section, _, _ := strings.Cut(c.Key, ".")
+	return section
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+type subscription struct {
+	section string
+	fn      func(cfg *Config)
+}
+
+// Watcher reloads the config when its source changes. A new config is validated, swapped in atomically
+// and handed to the subscribers of the sections that changed, an invalid one is logged and ignored.
+type Watcher struct {
+	loader    *loader
+	loaderErr error
+	logger    Logger
+
+	current atomic.Pointer[Config]
+	// mutex serializes reloads, so a file change cannot apply an older remote document than a concurrent poll
+	mutex sync.Mutex
+	// remote is the last config server document applied, file changes are layered below it
+	remote        []byte
+	subscriptions []subscription
+	done          chan struct{}
+	stopOnce      sync.Once
+}
+
+// Current returns the latest valid config
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe calls fn with the new config after a key below the top level section changed
+func (w *Watcher) Subscribe(section string, fn func(cfg *Config)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.subscriptions = append(w.subscriptions, subscription{section: section, fn: fn})
+}
+
+// Start watches the yaml files and, in REMOTE mode, polls the config server.
+// Environment variables and flags keep the values they had at startup.
+func (w *Watcher) Start() error {
+	if w.loaderErr != nil {
+		return w.loaderErr
+	}
+
+	base, env := w.loader.files()
+	for _, file := range []string{base, env} {
+		if _, err := os.Stat(file); file != "" && err == nil {
+			w.watchFile(file)
+		}
+	}
+
+	if w.loader.env == "REMOTE" {
+		return w.pollConfigServer()
+	}
+
+	return nil
+}
+
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *Watcher) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchFile only uses viper to follow the file, every change reloads all layers
+func (w *Watcher) watchFile(file string) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	v.OnConfigChange(func(e fsnotify.Event) {
+		if w.stopped() {
+			return
+		}
+
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+
+		w.reload(w.remote)
+	})
+	v.WatchConfig()
+}
+
+func (w *Watcher) pollConfigServer() error {
+	rc, err := newRemoteConfig()
+	if err != nil {
+		return err
+	}
+
+	interval, err := getEnvDuration(configServerPollIntervalKey, defaultConfigPollInterval)
+	if err != nil || interval <= 0 {
+		return err
+	}
+
+	w.mutex.Lock()
+	last := w.remote
+	w.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+			}
+
+			data, err := rc.fetch()
+			if err != nil {
+				w.logger.Warnf("Config server poll failed: %v", err)
+				continue
+			}
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+
+			w.mutex.Lock()
+			if w.reload(data) {
+				w.remote = data
+				rc.saveCache(data)
+			}
+			w.mutex.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// reload loads every layer with remote on top, mutex has to be held from reading remote until it is stored
+func (w *Watcher) reload(remote []byte) bool {
+	next := &Config{}
+	if _, err := w.loader.load(next, remote); err != nil {
+		w.logger.Errorf("Config reload failed, keeping the current config: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+		return false
+	}
+
+	return w.apply(next)
+}
+
+// apply swaps next in when it is valid and notifies the subscribers of the changed sections, mutex has to be held
+func (w *Watcher) apply(next *Config) bool {
+	if err := next.Validate(); err != nil {
+		w.logger.Errorf("Config reload rejected, keeping the current config: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+		return false
+	}
+
+	changes := Diff(w.current.Load(), next)
+	if len(changes) == 0 {
+		return true
+	}
+
+	w.current.Store(next)
+
+	sections := make(map[string]bool)
+	descriptions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		sections[change.Section()] = true
+		descriptions = append(descriptions, change.String())
+	}
+	w.logger.Infof("Config reloaded, Changes: %s", strings.Join(descriptions, ", "))
+
+	for _, s := range w.subscriptions {
+		if sections[s.section] {
+			s.fn(next)
+		}
+	}
+
+	return true
+}
+
+// Diff lists the keys whose values differ between old and new
+func Diff(old, new *Config) []Change {
+	return diffValues("", reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), nil)
+}
+
+func diffValues(key string, old, new reflect.Value, changes []Change) []Change {
+	if old.Kind() == reflect.Struct {
+		for i := 0; i < old.NumField(); i++ {
+			name := old.Type().Field(i).Tag.Get(tagName)
+			if key != "" {
+				name = key + "." + name
+			}
+			changes = diffValues(name, old.Field(i), new.Field(i), changes)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return changes
+	}
+
+	return append(changes, Change{Key: key, Old: redactValue(key, old), New: redactValue(key, new)})
+}
+
+// NewWatcher starts from cfg and, in REMOTE mode, the config server document cfg was read from.
+// Without that document a file change before the first poll would drop the config server layer.
+func NewWatcher(cfg *Config, remote []byte, logger Logger) *Watcher {
+	env, _ := os.LookupEnv(environmentKey)
+
+	w := &Watcher{
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	w.loader, w.loaderErr = newLoader(strings.ToUpper(env), os.Args[1:])
+	w.current.Store(cfg)
+	w.remote = remote
+
+	return w
+}
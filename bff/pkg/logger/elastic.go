@@ -0,0 +1,307 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"github.com/olivere/elastic/v7"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultElasticIndex         = "bff_log_index"
+	defaultElasticBatchSize     = 500
+	defaultElasticFlushInterval = time.Second
+	defaultElasticQueueSize     = 10000
+	defaultElasticMaxRetries    = 3
+	elasticFlushTimeout         = 5 * time.Second
+
+	QueuePolicyDrop  = "drop"
+	QueuePolicyBlock = "block"
+)
+
+// elasticCore is a zap core turning entries into documents for the shipper, it never writes synchronously
+type elasticCore struct {
+	zapcore.LevelEnabler
+	fields  []zapcore.Field
+	shipper *elasticShipper
+}
+
+func (c *elasticCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+
+	return &clone
+}
+
+func (c *elasticCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *elasticCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	doc := enc.Fields
+	doc["@timestamp"] = ent.Time.UTC().Format(time.RFC3339Nano)
+	doc["level"] = ent.Level.String()
+	doc["message"] = ent.Message
+	if ent.LoggerName != "" {
+		doc["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		doc["caller"] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" {
+		doc["stacktrace"] = ent.Stack
+	}
+
+	c.shipper.enqueue(doc)
+
+	// the process may exit right after panic and fatal entries
+	if ent.Level > zapcore.ErrorLevel {
+		return c.Sync()
+	}
+
+	return nil
+}
+
+func (c *elasticCore) Sync() error {
+	return c.shipper.flush()
+}
+
+// elasticShipper moves documents from a bounded queue into a bulk processor, which batches and retries them.
+// While Elasticsearch is unreachable the processor stops accepting documents and the queue policy applies.
+type elasticShipper struct {
+	cfg       config.ElasticLogConfig
+	client    *elastic.Client
+	processor *elastic.BulkProcessor
+	queue     chan map[string]interface{}
+	flushC    chan chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closed    atomic.Bool
+	dropped   atomic.Int64
+}
+
+func (s *elasticShipper) enqueue(doc map[string]interface{}) {
+	if s.closed.Load() {
+		s.dropped.Add(1)
+		return
+	}
+
+	if s.cfg.QueuePolicy == QueuePolicyBlock {
+		select {
+		case s.queue <- doc:
+		case <-s.done:
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case s.queue <- doc:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *elasticShipper) run() {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case doc := <-s.queue:
+			s.processor.Add(s.request(doc))
+		case flushed := <-s.flushC:
+			s.drain()
+			_ = s.processor.Flush()
+			close(flushed)
+		case <-s.done:
+			s.drain()
+			return
+		}
+	}
+}
+
+func (s *elasticShipper) drain() {
+	for {
+		select {
+		case doc := <-s.queue:
+			s.processor.Add(s.request(doc))
+		default:
+			return
+		}
+	}
+}
+
+func (s *elasticShipper) request(doc map[string]interface{}) elastic.BulkableRequest {
+	req := elastic.NewBulkIndexRequest().Index(s.cfg.Index).Doc(doc)
+	if s.cfg.DataStream {
+		// data streams only accept creates
+		req.OpType("create")
+	}
+
+	return req
+}
+
+// flush ships the queued documents, it gives up after elasticFlushTimeout so logging never hangs the caller
+func (s *elasticShipper) flush() error {
+	if s.closed.Load() {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case s.flushC <- flushed:
+	case <-s.stopped:
+		return nil
+	case <-time.After(elasticFlushTimeout):
+		return fmt.Errorf("elasticsearch log flush timed out after %s", elasticFlushTimeout)
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-time.After(elasticFlushTimeout):
+		return fmt.Errorf("elasticsearch log flush timed out after %s", elasticFlushTimeout)
+	}
+}
+
+// close ships what is left and stops the processor, later entries are dropped
+func (s *elasticShipper) close() error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(s.done)
+
+	closed := make(chan error, 1)
+	go func() {
+		<-s.stopped
+		err := s.processor.Close()
+		s.client.Stop()
+		closed <- err
+	}()
+
+	var err error
+	select {
+	case err = <-closed:
+	case <-time.After(elasticFlushTimeout):
+		err = fmt.Errorf("elasticsearch log shipper did not stop within %s", elasticFlushTimeout)
+	}
+
+	s.reportDropped()
+	return err
+}
+
+// after reports failed bulk requests on stderr, logging them would feed the queue that just failed
+func (s *elasticShipper) after(_ int64, requests []elastic.BulkableRequest, res *elastic.BulkResponse, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to ship %d log entries to Elasticsearch: %v\n", len(requests), err)
+	} else if res != nil && res.Errors {
+		fmt.Fprintf(os.Stderr, "Elasticsearch rejected %d of %d log entries\n", len(res.Failed()), len(requests))
+	}
+
+	s.reportDropped()
+}
+
+func (s *elasticShipper) reportDropped() {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		fmt.Fprintf(os.Stderr, "Dropped %d log entries, the Elasticsearch log queue was full\n", dropped)
+	}
+}
+
+// limitedBackoff stops retrying after maxRetries attempts
+type limitedBackoff struct {
+	backoff    elastic.Backoff
+	maxRetries int
+}
+
+func (b limitedBackoff) Next(retry int) (time.Duration, bool) {
+	if retry > b.maxRetries {
+		return 0, false
+	}
+
+	return b.backoff.Next(retry)
+}
+
+// newElasticCore connects lazily, so the service starts while Elasticsearch is unreachable
+func newElasticCore(cfg *config.Config, level zapcore.LevelEnabler) (*elasticCore, *elasticShipper, error) {
+	logCfg := cfg.Logger.Elastic
+	if logCfg.Index == "" {
+		logCfg.Index = defaultElasticIndex
+	}
+	if logCfg.BatchSize <= 0 {
+		logCfg.BatchSize = defaultElasticBatchSize
+	}
+	if logCfg.QueueSize <= 0 {
+		logCfg.QueueSize = defaultElasticQueueSize
+	}
+	if logCfg.MaxRetries <= 0 {
+		logCfg.MaxRetries = defaultElasticMaxRetries
+	}
+	flushInterval := time.Duration(logCfg.FlushInterval) * time.Millisecond
+	if flushInterval <= 0 {
+		flushInterval = defaultElasticFlushInterval
+	}
+	switch logCfg.QueuePolicy {
+	case "":
+		logCfg.QueuePolicy = QueuePolicyDrop
+	case QueuePolicyDrop, QueuePolicyBlock:
+	default:
+		return nil, nil, fmt.Errorf("unknown elasticsearch log queue policy %q", logCfg.QueuePolicy)
+	}
+
+	client, err := elastic.NewClient(
+		elastic.SetURL(cfg.Logger.ElasticSearchUrl),
+		elastic.SetSniff(false),
+		elastic.SetHealthcheck(false))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &elasticShipper{
+		cfg:     logCfg,
+		client:  client,
+		queue:   make(chan map[string]interface{}, logCfg.QueueSize),
+		flushC:  make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	s.processor, err = client.BulkProcessor().
+		Name("bff-logger").
+		Workers(1).
+		BulkActions(logCfg.BatchSize).
+		FlushInterval(flushInterval).
+		Backoff(limitedBackoff{
+			backoff:    elastic.NewExponentialBackoff(100*time.Millisecond, 5*time.Second),
+			maxRetries: logCfg.MaxRetries,
+		}).
+		RetryItemStatusCodes(http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout).
+		After(s.after).
+		Do(context.Background())
+	if err != nil {
+		client.Stop()
+		return nil, nil, err
+	}
+
+	go s.run()
+
+	return &elasticCore{LevelEnabler: level, shipper: s}, s, nil
+}
@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -22,11 +25,25 @@ type Logger interface {
 	Errorf(template string, args ...interface{})
 	DPanicf(template string, args ...interface{})
 	Fatalf(template string, args ...interface{})
+
+	// With returns a logger adding the key value pairs to every line it writes
+	With(args ...interface{}) Logger
+	// FromContext returns the request scoped logger stored in ctx by WithContext, or this logger when there is none
+	FromContext(ctx context.Context) Logger
+
+	// Level is the level of the sinks without their own one, SetLevel changes it at runtime
+	Level() string
+	SetLevel(level string) error
+
+	// Sync flushes buffered log entries, call it before the process exits
+	Sync() error
 }
 
 type logger struct {
 	cfg         *config.Config
 	sugarLogger *zap.SugaredLogger
+	level       zap.AtomicLevel
+	closers     *closers
 }
 
 var loggerLevelMap = map[string]zapcore.Level{
@@ -49,61 +66,67 @@ func (l *logger) getLogLevel(cfg *config.Config) zapcore.Level {
 }
 
 func (l *logger) InitLogger() {
-	logLevel := l.getLogLevel(l.cfg)
-	logWriter := zapcore.AddSync(os.Stderr)
-
-	var encoderCfg zapcore.EncoderConfig
-	if l.cfg.Server.Mode == "Dev" {
-		encoderCfg = zap.NewDevelopmentEncoderConfig()
-	} else {
-		encoderCfg = zap.NewProductionEncoderConfig()
+	l.level = zap.NewAtomicLevelAt(l.getLogLevel(l.cfg))
+	l.closers = &closers{}
+
+	sinks := l.cfg.Logger.Sinks
+	if len(sinks) == 0 {
+		sinks = defaultSinks
 	}
 
-	var encoder zapcore.Encoder
-	encoderCfg.LevelKey = "LEVEL"
-	encoderCfg.CallerKey = "CALLER"
-	encoderCfg.TimeKey = "TIME"
-	encoderCfg.NameKey = "NAME"
-	encoderCfg.MessageKey = "MESSAGE"
-
-	if l.cfg.Logger.Encoding == "console" {
-		encoder = zapcore.NewConsoleEncoder(encoderCfg)
-	} else {
-		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	// a sink that cannot be set up is skipped so logging never stops the service from starting
+	var cores []zapcore.Core
+	var sinkErrs []error
+	for _, sink := range sinks {
+		core, closer, err := l.newSinkCore(sink)
+		if err != nil {
+			sinkErrs = append(sinkErrs, fmt.Errorf("sink: %s, error: %w", sink.Type, err))
+			continue
+		}
+		cores = append(cores, core)
+		if closer != nil {
+			l.closers.fns = append(l.closers.fns, closer)
+		}
+	}
+	if len(cores) == 0 {
+		cores = append(cores, zapcore.NewCore(l.newEncoder(l.cfg.Logger.Encoding), zapcore.Lock(os.Stderr), l.level))
 	}
 
-	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	core := zapcore.NewCore(encoder, logWriter, zap.NewAtomicLevelAt(logLevel))
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
+	core := zapcore.NewTee(cores...)
+	if l.cfg.Logger.Sampling.Enabled {
+		core = newSampler(core, l.cfg.Logger.Sampling)
+	}
 
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
 	l.sugarLogger = logger.Sugar()
-	if err := l.sugarLogger.Sync(); err != nil {
-		l.sugarLogger.Error(err)
+
+	for _, err := range sinkErrs {
+		l.sugarLogger.Errorf("Failed to initialize log %v", err)
 	}
 }
 
 func (l logger) Debug(args ...interface{}) {
-	l.sugarLogger.Debug(args)
+	l.sugarLogger.Debug(args...)
 }
 
 func (l logger) Info(args ...interface{}) {
-	l.sugarLogger.Info(args)
+	l.sugarLogger.Info(args...)
 }
 
 func (l logger) Warn(args ...interface{}) {
-	l.sugarLogger.Warn(args)
+	l.sugarLogger.Warn(args...)
 }
 
 func (l logger) Error(args ...interface{}) {
-	l.sugarLogger.Error(args)
+	l.sugarLogger.Error(args...)
 }
 
 func (l logger) DPanic(args ...interface{}) {
-	l.sugarLogger.DPanic(args)
+	l.sugarLogger.DPanic(args...)
 }
 
 func (l logger) Fatal(args ...interface{}) {
-	l.sugarLogger.Fatal(args)
+	l.sugarLogger.Fatal(args...)
 }
 
 func (l logger) Debugf(template string, args ...interface{}) {
@@ -130,6 +153,42 @@ func (l logger) Fatalf(template string, args ...interface{}) {
 	l.sugarLogger.Fatalf(template, args...)
 }
 
+func (l *logger) With(args ...interface{}) Logger {
+	return &logger{
+		cfg:         l.cfg,
+		sugarLogger: l.sugarLogger.With(args...),
+		level:       l.level,
+		closers:     l.closers,
+	}
+}
+
+func (l *logger) FromContext(ctx context.Context) Logger {
+	if ctxLogger, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return ctxLogger
+	}
+
+	return l
+}
+
+func (l *logger) Level() string {
+	return l.level.Level().String()
+}
+
+func (l *logger) SetLevel(level string) error {
+	logLevel, exist := loggerLevelMap[level]
+	if !exist {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	l.level.SetLevel(logLevel)
+
+	return nil
+}
+
+// Sync flushes every sink and closes them afterwards, entries logged later may be lost
+func (l *logger) Sync() error {
+	return errors.Join(l.sugarLogger.Sync(), l.closers.close())
+}
+
 func NewLogger(cfg *config.Config) Logger {
 	return &logger{
 		cfg: cfg,
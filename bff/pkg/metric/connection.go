@@ -0,0 +1,58 @@
+package metric
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+var connectivityStates = []connectivity.State{
+	connectivity.Idle,
+	connectivity.Connecting,
+	connectivity.Ready,
+	connectivity.TransientFailure,
+	connectivity.Shutdown,
+}
+
+// WatchConnectionState exports the state of conn, 1 for the current state and 0 for the others, and counts
+// the transitions into each state until ctx is done or conn is closed. Both live in the default registry
+// and are scraped from the server NewServer returns.
+func WatchConnectionState(ctx context.Context, conn *grpc.ClientConn, name, target string) error {
+	states := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_grpc_connection_state",
+	}, []string{"target", "state"})
+	if err := prometheus.Register(states); err != nil {
+		return err
+	}
+
+	transitions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_grpc_connection_transitions",
+	}, []string{"target", "state"})
+	if err := prometheus.Register(transitions); err != nil {
+		return err
+	}
+
+	set := func(current connectivity.State) {
+		for _, state := range connectivityStates {
+			value := 0.0
+			if state == current {
+				value = 1
+			}
+			states.WithLabelValues(target, state.String()).Set(value)
+		}
+	}
+
+	go func() {
+		state := conn.GetState()
+		set(state)
+
+		for state != connectivity.Shutdown && conn.WaitForStateChange(ctx, state) {
+			state = conn.GetState()
+			set(state)
+			transitions.WithLabelValues(target, state.String()).Inc()
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scrape reads /metrics the way prometheus does, through the handler of the metric server
+func scrape(t *testing.T, server *http.Server) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+
+	return string(body)
+}
+
+// runs keeps the metric names of repeated runs apart, the default registry outlives a single test
+var runs atomic.Int32
+
+func TestWatchConnectionStateIsServed(t *testing.T) {
+	const target = "localhost:1"
+	name := fmt.Sprintf("test%d", runs.Add(1))
+
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConnectionState(ctx, conn, name, target); err != nil {
+		t.Fatalf("WatchConnectionState: %v", err)
+	}
+
+	server := NewServer("")
+
+	// the state the connection started in has to be exported before closing it counts as a transition
+	waitFor(t, server, name+`_grpc_connection_state{`)
+	_ = conn.Close()
+
+	waitFor(t, server,
+		name+`_grpc_connection_state{state="SHUTDOWN",target="localhost:1"} 1`,
+		name+`_grpc_connection_transitions{state="SHUTDOWN",target="localhost:1"} 1`,
+	)
+}
+
+// waitFor scrapes server until every one of want shows up
+func waitFor(t *testing.T, server *http.Server, want ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		body := scrape(t, server)
+		missing := ""
+		for _, line := range want {
+			if !strings.Contains(body, line) {
+				missing = line
+				break
+			}
+		}
+		if missing == "" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics do not contain %q:\n%s", missing, body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
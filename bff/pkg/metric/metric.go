@@ -2,6 +2,8 @@ package metric
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
 	"strconv"
 )
 
@@ -25,7 +27,7 @@ func (p *PrometheusMetrics) ObserveResponseTime(status int, method, path string,
 	p.Times.WithLabelValues(strconv.Itoa(status), method, path).Observe(observeTime)
 }
 
-func CreateMetric(address, name string) (Metrics, error) {
+func CreateMetric(name string) (Metrics, error) {
 	var prometheusMetric PrometheusMetrics
 	prometheusMetric.HitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: name + "_hits_total",
@@ -50,3 +52,15 @@ func CreateMetric(address, name string) (Metrics, error) {
 
 	return &prometheusMetric, nil
 }
+
+// NewServer serves every registered metric on address, the default registry already carries
+// the Go runtime and process collectors next to the connection state gauge.
+func NewServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+}
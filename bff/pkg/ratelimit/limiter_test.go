@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultRule   Rule
+		rules         map[string]Rule
+		route         string
+		requests      int
+		wantAllowed   int
+		wantLimit     int
+		wantRemaining int
+	}{
+		{
+			name:        "burst is spent",
+			defaultRule: Rule{Rate: 1, Burst: 3},
+			route:       "GET /products",
+			requests:    5,
+			wantAllowed: 3,
+			wantLimit:   3,
+		},
+		{
+			name:          "tokens left",
+			defaultRule:   Rule{Rate: 1, Burst: 3},
+			route:         "GET /products",
+			requests:      1,
+			wantAllowed:   1,
+			wantLimit:     3,
+			wantRemaining: 2,
+		},
+		{
+			name:        "route rule wins over the default",
+			defaultRule: Rule{Rate: 1, Burst: 3},
+			rules:       map[string]Rule{"POST /products": {Rate: 1, Burst: 1}},
+			route:       "POST /products",
+			requests:    3,
+			wantAllowed: 1,
+			wantLimit:   1,
+		},
+		{
+			name:        "zero rate disables limiting",
+			defaultRule: Rule{Rate: 1, Burst: 1},
+			rules:       map[string]Rule{"GET /health": {}},
+			route:       "GET /health",
+			requests:    5,
+			wantAllowed: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.defaultRule, tt.rules)
+
+			var allowed int
+			var last Result
+			for i := 0; i < tt.requests; i++ {
+				last = l.Allow(tt.route, "client")
+				if last.Allowed {
+					allowed++
+				}
+			}
+
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed = %d, want %d", allowed, tt.wantAllowed)
+			}
+			if last.Limit != tt.wantLimit {
+				t.Errorf("Limit = %d, want %d", last.Limit, tt.wantLimit)
+			}
+			if last.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", last.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestLimiterKeepsABucketPerRouteAndClient(t *testing.T) {
+	l := NewLimiter(Rule{Rate: 1, Burst: 1}, nil)
+
+	if !l.Allow("GET /products", "a").Allowed {
+		t.Fatal("first request of a was rejected")
+	}
+	if l.Allow("GET /products", "a").Allowed {
+		t.Error("second request of a was allowed")
+	}
+	if !l.Allow("GET /products", "b").Allowed {
+		t.Error("first request of b was rejected")
+	}
+	if !l.Allow("GET /products/:id", "a").Allowed {
+		t.Error("first request of a on another route was rejected")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := NewLimiter(Rule{Rate: 50, Burst: 1}, nil)
+
+	if !l.Allow("GET /products", "client").Allowed {
+		t.Fatal("first request was rejected")
+	}
+
+	rejected := l.Allow("GET /products", "client")
+	if rejected.Allowed {
+		t.Fatal("request on an empty bucket was allowed")
+	}
+	if rejected.RetryAfter <= 0 || rejected.RetryAfter > 20*time.Millisecond {
+		t.Errorf("RetryAfter = %s, want within the 20ms refill of one token", rejected.RetryAfter)
+	}
+	if rejected.Reset <= 0 || rejected.Reset > 20*time.Millisecond {
+		t.Errorf("Reset = %s, want within the 20ms refill of a full bucket", rejected.Reset)
+	}
+
+	time.Sleep(rejected.RetryAfter + 5*time.Millisecond)
+	if !l.Allow("GET /products", "client").Allowed {
+		t.Error("request after the refill was rejected")
+	}
+}
+
+func TestLimiterEvictsIdleBuckets(t *testing.T) {
+	l := NewLimiter(Rule{Rate: 1, Burst: 1}, nil)
+	l.Allow("GET /products", "idle")
+	l.Allow("GET /products", "active")
+
+	now := time.Now()
+	l.buckets["GET /products|idle"].lastSeen = now.Add(-idleTimeout - time.Second)
+	l.buckets["GET /products|active"].lastSeen = now.Add(-idleTimeout + time.Minute)
+
+	// no sweep before the cleanup interval passed
+	l.Allow("GET /products", "other")
+	if _, exist := l.buckets["GET /products|idle"]; !exist {
+		t.Fatal("idle bucket was dropped before the cleanup interval")
+	}
+
+	l.lastCleanup = now.Add(-cleanupInterval - time.Second)
+	l.Allow("GET /products", "other")
+
+	if _, exist := l.buckets["GET /products|idle"]; exist {
+		t.Error("idle bucket was kept")
+	}
+	if _, exist := l.buckets["GET /products|active"]; !exist {
+		t.Error("active bucket was dropped")
+	}
+	if !l.Allow("GET /products", "idle").Allowed {
+		t.Error("client of an evicted bucket did not start over with a full bucket")
+	}
+}
+
+func TestLimiterUpdate(t *testing.T) {
+	l := NewLimiter(Rule{Rate: 1, Burst: 1}, nil)
+	l.Allow("GET /products", "client")
+	if l.Allow("GET /products", "client").Allowed {
+		t.Fatal("request on an empty bucket was allowed")
+	}
+
+	l.Update(Rule{Rate: 1, Burst: 2}, nil)
+
+	result := l.Allow("GET /products", "client")
+	if !result.Allowed {
+		t.Error("request after Update was rejected")
+	}
+	if result.Limit != 2 {
+		t.Errorf("Limit = %d, want the updated burst 2", result.Limit)
+	}
+}
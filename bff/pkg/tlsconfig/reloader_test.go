@@ -0,0 +1,115 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate for localhost, it is its own CA
+func writeKeyPair(t *testing.T) config.TLSConfig {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(dir, "server.crt"),
+		KeyFile:    filepath.Join(dir, "server.key"),
+		CAFile:     filepath.Join(dir, "server.crt"),
+		ServerName: "localhost",
+		ClientAuth: "none",
+	}
+	if err := os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg
+}
+
+func TestServerConfigNegotiatesProtocol(t *testing.T) {
+	cfg := writeKeyPair(t)
+	reloader, err := NewReloader(cfg, logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	defer reloader.Close()
+
+	tests := []struct {
+		name         string
+		serverProtos []string
+		clientProtos []string
+		want         string
+	}{
+		{name: "grpc", serverProtos: []string{"h2"}, clientProtos: []string{"h2"}, want: "h2"},
+		{name: "http/1.1 client on a web listener", serverProtos: []string{"h2", "http/1.1"}, clientProtos: []string{"http/1.1"}, want: "http/1.1"},
+		{name: "h2 client on a web listener", serverProtos: []string{"h2", "http/1.1"}, clientProtos: []string{"h2", "http/1.1"}, want: "h2"},
+		{name: "client without ALPN", serverProtos: []string{"h2"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			defer clientConn.Close()
+
+			server := tls.Server(serverConn, reloader.ServerConfig(tt.serverProtos...))
+			serverErr := make(chan error, 1)
+			go func() {
+				serverErr <- server.Handshake()
+			}()
+
+			clientConfig := reloader.ClientConfig()
+			clientConfig.NextProtos = tt.clientProtos
+			client := tls.Client(clientConn, clientConfig)
+			if err := client.Handshake(); err != nil {
+				t.Fatalf("client handshake: %v", err)
+			}
+			if err := <-serverErr; err != nil {
+				t.Fatalf("server handshake: %v", err)
+			}
+
+			if got := client.ConnectionState().NegotiatedProtocol; got != tt.want {
+				t.Errorf("negotiated protocol = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
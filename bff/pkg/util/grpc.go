@@ -0,0 +1,56 @@
+package util
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderRetryAfter tells throttled callers how many seconds to wait before they try again
+const HeaderRetryAfter = "Retry-After"
+
+var grpcCodeHttpStatusMap = map[codes.Code]int{
+	codes.InvalidArgument:   http.StatusBadRequest,
+	codes.NotFound:          http.StatusNotFound,
+	codes.AlreadyExists:     http.StatusConflict,
+	codes.Unavailable:       http.StatusServiceUnavailable,
+	codes.Unauthenticated:   http.StatusUnauthorized,
+	codes.PermissionDenied:  http.StatusForbidden,
+	codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+	codes.ResourceExhausted: http.StatusTooManyRequests,
+}
+
+func GetHttpStatusFromGrpcError(err error) int {
+	httpStatus, exist := grpcCodeHttpStatusMap[status.Code(err)]
+	if !exist {
+		return http.StatusInternalServerError
+	}
+
+	return httpStatus
+}
+
+func NewHttpResponseFromGrpcError(err error) HttpResponse {
+	return NewHttpResponse(GetHttpStatusFromGrpcError(err), status.Convert(err).Message(), nil)
+}
+
+// GetRetryDelay returns the RetryInfo detail the product service attaches to throttled calls
+func GetRetryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			return info.RetryDelay.AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+// SetRetryAfter copies the retry delay of err into the Retry-After header, in seconds rounded up
+func SetRetryAfter(header http.Header, err error) {
+	if delay, ok := GetRetryDelay(err); ok {
+		header.Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+}
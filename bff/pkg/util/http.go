@@ -3,13 +3,96 @@ package util
 import (
 	"context"
 	"github.com/labstack/echo/v4"
+	"github.com/sefikcan/ms-grpc-sample/bff/pkg/auth"
 	"github.com/sefikcan/ms-grpc-sample/bff/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// HeaderXRequestID is the gRPC metadata key the request id is propagated with
+var HeaderXRequestID = strings.ToLower(echo.HeaderXRequestID)
+
+// HeaderXTenantID carries the tenant of the caller, it is logged and forwarded to the product service
+const HeaderXTenantID = "X-Tenant-Id"
+
+// HeaderConsistencyToken is returned from mutations, sending it back on reads makes them observe that write
+const HeaderConsistencyToken = "X-Consistency-Token"
+
+// Echo context keys set by the auth middleware
+const (
+	PrincipalKey     = "principal"
+	AuthorizationKey = "authorization"
 )
 
 func GetRequestId(c echo.Context) string {
 	return c.Response().Header().Get(echo.HeaderXRequestID)
 }
 
+// GetTraceId returns the trace id of the server span of the request, empty when it is not traced
+func GetTraceId(c echo.Context) string {
+	spanContext := trace.SpanContextFromContext(c.Request().Context())
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
+func GetSpanId(c echo.Context) string {
+	spanContext := trace.SpanContextFromContext(c.Request().Context())
+	if !spanContext.HasSpanID() {
+		return ""
+	}
+
+	return spanContext.SpanID().String()
+}
+
+func GetTenant(c echo.Context) string {
+	return c.Request().Header.Get(HeaderXTenantID)
+}
+
+func GetConsistencyToken(c echo.Context) string {
+	return c.Request().Header.Get(HeaderConsistencyToken)
+}
+
+func SetConsistencyToken(c echo.Context, token string) {
+	if token != "" {
+		c.Response().Header().Set(HeaderConsistencyToken, token)
+	}
+}
+
+func GetPrincipal(c echo.Context) (*auth.Principal, bool) {
+	principal, ok := c.Get(PrincipalKey).(*auth.Principal)
+	return principal, ok
+}
+
+func GetSubject(c echo.Context) string {
+	if principal, ok := GetPrincipal(c); ok {
+		return principal.Subject
+	}
+
+	return ""
+}
+
+// GetGrpcCtx derives the call context from the HTTP request, so a disconnecting client cancels the call,
+// and carries the request id, the tenant and the verified principal to the product service as metadata
+func GetGrpcCtx(c echo.Context) context.Context {
+	md := metadata.Pairs(HeaderXRequestID, GetRequestId(c))
+	if principal, ok := GetPrincipal(c); ok {
+		md.Set(auth.MetadataPrincipalSubject, principal.Subject)
+		md.Set(auth.MetadataPrincipalRoles, principal.RolesString())
+	}
+	if authorization, ok := c.Get(AuthorizationKey).(string); ok {
+		md.Set(auth.MetadataAuthorization, authorization)
+	}
+	if tenant := GetTenant(c); tenant != "" {
+		md.Set(strings.ToLower(HeaderXTenantID), tenant)
+	}
+
+	return metadata.NewOutgoingContext(c.Request().Context(), md)
+}
+
 func GetIPAddress(c echo.Context) string {
 	return c.Request().RemoteAddr
 }
@@ -19,5 +102,5 @@ func GetRequestCtx(c echo.Context) context.Context {
 }
 
 func PrepareLogging(ctx echo.Context, logger logger.Logger, err error) {
-	logger.Errorf("Error, RequestId: %s, IPAddress: %s, Error: %s", GetRequestId(ctx), GetIPAddress(ctx), err)
+	logger.FromContext(ctx.Request().Context()).Errorf("Error, IPAddress: %s, Error: %s", GetIPAddress(ctx), err)
 }
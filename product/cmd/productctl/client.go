@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"strings"
+)
+
+type cli struct {
+	opts   *options
+	conn   *grpc.ClientConn
+	client pb.ProductServiceClient
+}
+
+func newCli(opts *options) (*cli, error) {
+	transportCredentials, err := newTransportCredentials(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.Dial(opts.address, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cli{
+		opts:   opts,
+		conn:   conn,
+		client: pb.NewProductServiceClient(conn),
+	}, nil
+}
+
+func (c *cli) close() {
+	_ = c.conn.Close()
+}
+
+// context returns a context bounded by -timeout that carries the -H metadata
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.timeout)
+
+	for _, pair := range c.opts.metadata {
+		key, value, _ := strings.Cut(pair, "=")
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(key), value)
+	}
+
+	return ctx, cancel
+}
+
+func newTransportCredentials(opts *options) (credentials.TransportCredentials, error) {
+	if !opts.tls && opts.caFile == "" && opts.certFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         opts.serverName,
+		InsecureSkipVerify: opts.insecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if opts.caFile != "" {
+		ca, err := os.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.certFile != "" || opts.keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ghodss/yaml"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// parseArgs allows the id to come before or after the command flags
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if id == "" && fs.NArg() > 0 {
+		id = fs.Arg(0)
+	}
+
+	return id, nil
+}
+
+func (c *cli) get(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	token := fs.String("consistency-token", "", "token returned from a mutation to read your own write")
+	id, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return errors.New("get requires a product id")
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.client.GetProductDetail(ctx, &pb.GetProductDetailRequest{Id: id, ConsistencyToken: *token})
+	if err != nil {
+		return err
+	}
+
+	return c.print(res, detailRow(res))
+}
+
+func (c *cli) create(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	name := fs.String("name", "", "product name")
+	category := fs.String("category", "", "product category")
+	optionName := fs.String("option-name", "", "product option name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.client.CreateProduct(ctx, &pb.CreateProductRequest{Name: *name, Category: *category, OptionName: *optionName})
+	if err != nil {
+		return err
+	}
+
+	return c.print(res, productRow{Id: res.Id, Name: res.Name, Category: res.Category, OptionName: res.OptionName})
+}
+
+func (c *cli) update(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	name := fs.String("name", "", "product name")
+	category := fs.String("category", "", "product category")
+	optionName := fs.String("option-name", "", "product option name")
+	id, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return errors.New("update requires a product id")
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.client.UpdateProduct(ctx, &pb.UpdateProductRequest{Id: id, Name: *name, Category: *category, OptionName: *optionName})
+	if err != nil {
+		return err
+	}
+
+	return c.print(res, productRow{Id: res.Id, Name: res.Name, Category: res.Category, OptionName: res.OptionName})
+}
+
+func (c *cli) delete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	id, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return errors.New("delete requires a product id")
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.client.DeleteProduct(ctx, &pb.DeleteProductRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	if c.opts.output == "table" {
+		fmt.Printf("Product %s deleted\n", id)
+		return nil
+	}
+
+	return c.print(res)
+}
+
+func (c *cli) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	category := fs.String("category", "", "only list products of this category")
+	query := fs.String("query", "", "only list products whose name contains this text")
+	pageSize := fs.Int("page-size", 20, "products per page")
+	pageToken := fs.String("page-token", "", "next page token of a previous list")
+	all := fs.Bool("all", false, "follow next page tokens until every product is listed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	res, err := c.listProducts(*category, *query, int32(*pageSize), *pageToken, *all)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]productRow, 0, len(res.Products))
+	for _, product := range res.Products {
+		rows = append(rows, detailRow(product))
+	}
+
+	return c.print(res, rows...)
+}
+
+func (c *cli) listProducts(category, query string, pageSize int32, pageToken string, all bool) (*pb.ListProductsResponse, error) {
+	res := &pb.ListProductsResponse{}
+	for {
+		ctx, cancel := c.context()
+		page, err := c.client.ListProducts(ctx, &pb.ListProductsRequest{Category: category, Query: query, PageSize: pageSize, PageToken: pageToken})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		res.Products = append(res.Products, page.Products...)
+		res.NextPageToken = page.NextPageToken
+		if !all || page.NextPageToken == "" {
+			return res, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+type watchEvent struct {
+	Type    string                       `json:"type"`
+	Product *pb.GetProductDetailResponse `json:"product"`
+}
+
+// watch polls the product, or every product of a category, and prints what was added, modified or deleted
+func (c *cli) watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	category := fs.String("category", "", "only watch products of this category")
+	interval := fs.Duration("interval", 2*time.Second, "poll interval")
+	id, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	previous := map[string]*pb.GetProductDetailResponse{}
+	for {
+		current, err := c.snapshot(id, *category)
+		if err != nil {
+			return err
+		}
+
+		for productId, product := range current {
+			old, exist := previous[productId]
+			switch {
+			case !exist:
+				err = c.printEvent("ADDED", product)
+			case detailRow(old) != detailRow(product):
+				err = c.printEvent("MODIFIED", product)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for productId, product := range previous {
+			if _, exist := current[productId]; !exist {
+				if err := c.printEvent("DELETED", product); err != nil {
+					return err
+				}
+			}
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *cli) snapshot(id, category string) (map[string]*pb.GetProductDetailResponse, error) {
+	products := map[string]*pb.GetProductDetailResponse{}
+
+	if id != "" {
+		ctx, cancel := c.context()
+		defer cancel()
+
+		res, err := c.client.GetProductDetail(ctx, &pb.GetProductDetailRequest{Id: id})
+		if status.Code(err) == codes.NotFound {
+			return products, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		products[res.Id] = res
+		return products, nil
+	}
+
+	res, err := c.listProducts(category, "", 100, "", true)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range res.Products {
+		products[product.Id] = product
+	}
+
+	return products, nil
+}
+
+func (c *cli) printEvent(eventType string, product *pb.GetProductDetailResponse) error {
+	if c.opts.output == "table" {
+		return printTable([]productRow{detailRow(product)}, eventType)
+	}
+
+	out, err := marshal(product, "json")
+	if err != nil {
+		return err
+	}
+
+	event, err := json.Marshal(map[string]json.RawMessage{
+		"type":    json.RawMessage(`"` + eventType + `"`),
+		"product": out,
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.opts.output == "yaml" {
+		if event, err = yaml.JSONToYAML(event); err != nil {
+			return err
+		}
+		fmt.Println("---")
+	}
+	fmt.Println(string(event))
+
+	return nil
+}
+
+type importProduct struct {
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	OptionName string `json:"optionName"`
+}
+
+// importFile creates every product of a json or yaml file holding a list of products
+func (c *cli) importFile(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("f", "", "json or yaml file with a list of products")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("import requires -f <file>")
+	}
+
+	content, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(*file)) {
+	case ".yaml", ".yml":
+		if content, err = yaml.YAMLToJSON(content); err != nil {
+			return err
+		}
+	}
+
+	var products []importProduct
+	if err := json.Unmarshal(content, &products); err != nil {
+		return fmt.Errorf("%s must contain a list of products: %w", *file, err)
+	}
+
+	res := &pb.ListProductsResponse{}
+	rows := make([]productRow, 0, len(products))
+	var errs []error
+	for i, product := range products {
+		ctx, cancel := c.context()
+		created, err := c.client.CreateProduct(ctx, &pb.CreateProductRequest{Name: product.Name, Category: product.Category, OptionName: product.OptionName})
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("product %d (%s): %w", i+1, product.Name, err))
+			continue
+		}
+
+		detail := &pb.GetProductDetailResponse{Id: created.Id, Name: created.Name, Category: created.Category, OptionName: created.OptionName}
+		res.Products = append(res.Products, detail)
+		rows = append(rows, detailRow(detail))
+	}
+
+	if err := c.print(res, rows...); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
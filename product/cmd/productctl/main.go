@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type options struct {
+	address            string
+	output             string
+	timeout            time.Duration
+	tls                bool
+	caFile             string
+	certFile           string
+	keyFile            string
+	serverName         string
+	insecureSkipVerify bool
+	metadata           metadataFlag
+}
+
+// metadataFlag collects repeated -H key=value flags
+type metadataFlag []string
+
+func (m *metadataFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *metadataFlag) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("metadata must be key=value, got %q", value)
+	}
+	*m = append(*m, value)
+	return nil
+}
+
+type command struct {
+	usage string
+	run   func(cli *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"get":    {usage: "get <id>", run: (*cli).get},
+	"create": {usage: "create -name <name> -category <category> -option-name <option>", run: (*cli).create},
+	"update": {usage: "update <id> -name <name> -category <category> -option-name <option>", run: (*cli).update},
+	"delete": {usage: "delete <id>", run: (*cli).delete},
+	"list":   {usage: "list [-category <category>] [-page-size <n>] [-all]", run: (*cli).list},
+	"watch":  {usage: "watch [<id>] [-category <category>] [-interval <duration>]", run: (*cli).watch},
+	"import": {usage: "import -f <products.json|products.yaml>", run: (*cli).importFile},
+}
+
+var commandOrder = []string{"get", "create", "update", "delete", "list", "watch", "import"}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.address, "addr", "localhost:50053", "product service address")
+	flag.StringVar(&opts.output, "o", "table", "output format: table, json or yaml")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "timeout for each RPC")
+	flag.BoolVar(&opts.tls, "tls", false, "use TLS to connect")
+	flag.StringVar(&opts.caFile, "ca", "", "CA certificate to verify the server with")
+	flag.StringVar(&opts.certFile, "cert", "", "client certificate for mutual TLS")
+	flag.StringVar(&opts.keyFile, "key", "", "client key for mutual TLS")
+	flag.StringVar(&opts.serverName, "server-name", "", "override the server name used to verify the certificate")
+	flag.BoolVar(&opts.insecureSkipVerify, "insecure-skip-verify", false, "do not verify the server certificate")
+	flag.Var(&opts.metadata, "H", "metadata sent with every RPC as key=value, can be repeated")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	c, err := newCli(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	defer c.close()
+
+	if err := cmd.run(c, flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "productctl manages products through the ProductService gRPC API")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Usage: productctl [flags] <command> [command flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
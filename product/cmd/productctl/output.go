@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ghodss/yaml"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"os"
+	"text/tabwriter"
+)
+
+type productRow struct {
+	Id         string
+	Name       string
+	Category   string
+	OptionName string
+}
+
+func detailRow(p *pb.GetProductDetailResponse) productRow {
+	return productRow{Id: p.Id, Name: p.Name, Category: p.Category, OptionName: p.OptionName}
+}
+
+// print writes message as json or yaml, or rows as a table for the default output
+func (c *cli) print(message proto.Message, rows ...productRow) error {
+	switch c.opts.output {
+	case "json", "yaml":
+		out, err := marshal(message, c.opts.output)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	case "table":
+		return printTable(rows, "")
+	default:
+		return fmt.Errorf("unknown output format %q", c.opts.output)
+	}
+}
+
+func marshal(message proto.Message, format string) ([]byte, error) {
+	out, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "yaml" {
+		return yaml.JSONToYAML(out)
+	}
+
+	return out, nil
+}
+
+// printTable writes rows under a header, eventType adds a leading TYPE column for watch
+func printTable(rows []productRow, eventType string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if eventType == "" {
+		fmt.Fprintln(w, "ID\tNAME\tCATEGORY\tOPTION NAME")
+	}
+	for _, row := range rows {
+		if eventType != "" {
+			fmt.Fprintf(w, "%s\t", eventType)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Id, row.Name, row.Category, row.OptionName)
+	}
+
+	return w.Flush()
+}
@@ -2,25 +2,53 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/labstack/gommon/log"
+	"github.com/sefikcan/ms-grpc-sample/product/internal/healthcheck"
+	"github.com/sefikcan/ms-grpc-sample/product/internal/interceptors"
 	"github.com/sefikcan/ms-grpc-sample/product/internal/repository"
 	"github.com/sefikcan/ms-grpc-sample/product/internal/use_case"
+	"github.com/sefikcan/ms-grpc-sample/product/internal/web"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/admin"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/auth"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/metric"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/storage/mongo"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/tlsconfig"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const defaultShutdownTimeout = 30 * time.Second
+
 func main() {
 	log.Info("Starting product api server")
 
-	cfg := config.NewConfig()
+	cfg, remoteConfig := config.NewConfig()
 
 	zapLogger := logger.NewLogger(cfg)
 	zapLogger.InitLogger()
-	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, false)
+	zapLogger.Infof("AppVersion: %s, LogLevel: %s, Mode: %s, SSL: %v", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode, cfg.Server.TLS.Enabled)
+
+	tracerProvider, err := tracing.NewTracerProvider(cfg, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("Failed to initialize tracing: %v\n", err)
+	}
 
 	serverAddress := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 
@@ -30,9 +58,111 @@ func main() {
 	}
 	zapLogger.Infof("Listening on %s\n", serverAddress)
 
-	grpcServer := grpc.NewServer()
+	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	var webTLS *tls.Config
+	if cfg.Server.TLS.Enabled {
+		tlsReloader, err := tlsconfig.NewReloader(cfg.Server.TLS, zapLogger)
+		if err != nil {
+			zapLogger.Fatalf("Failed to load TLS certificates: %v\n", err)
+		}
+		defer tlsReloader.Close()
+
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsReloader.ServerConfig(http2.NextProtoTLS))))
+
+		if cfg.Server.Web.Enabled {
+			webTLSConfig := cfg.Server.TLS
+			webTLSConfig.ClientAuth = cfg.Server.Web.ClientAuth
+			webTLSReloader, err := tlsconfig.NewReloader(webTLSConfig, zapLogger)
+			if err != nil {
+				zapLogger.Fatalf("Failed to load web TLS certificates: %v\n", err)
+			}
+			defer webTLSReloader.Close()
+
+			webTLS = webTLSReloader.ServerConfig(http2.NextProtoTLS, "http/1.1")
+		}
+	}
+
+	var verifier *auth.Verifier
+	if cfg.Auth.Enabled {
+		verifier, err = auth.NewVerifier(cfg.Auth)
+		if err != nil {
+			zapLogger.Fatalf("Failed to initialize auth: %v\n", err)
+		}
+	}
+
+	metrics, err := metric.CreateMetric(cfg.Metric.ServiceName)
+	if err != nil {
+		zapLogger.Fatalf("CreateMetric error: %s", err)
+	}
+
+	metricServer := metric.NewServer(cfg.Metric.Url)
+	go func() {
+		zapLogger.Infof("Metrics available URL: %s, ServiceName: %s", cfg.Metric.Url, cfg.Metric.ServiceName)
+		if err := metricServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zapLogger.Errorf("Error starting metric server: %v", err)
+		}
+	}()
+
+	configWatcher := config.NewWatcher(cfg, remoteConfig, zapLogger)
+
+	var adminServer *http.Server
+	if cfg.Admin.Enabled {
+		adminServer, err = admin.NewServer(cfg, configWatcher.Current, zapLogger, verifier)
+		if err != nil {
+			zapLogger.Fatalf("Failed to create admin server: %v\n", err)
+		}
+
+		go func() {
+			zapLogger.Infof("Admin server is listening on %s", adminServer.Addr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				zapLogger.Errorf("Error starting admin server: %v", err)
+			}
+		}()
+	}
+
+	interceptorManager := interceptors.NewInterceptorManager(cfg, zapLogger)
+	rateLimiter := interceptorManager.NewRateLimiter()
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(
+			interceptorManager.RequestIdUnaryInterceptor,
+			interceptorManager.RequestLoggerUnaryInterceptor,
+			interceptorManager.MetricUnaryInterceptor(metrics),
+			interceptorManager.RecoveryUnaryInterceptor,
+			interceptorManager.DeadlineUnaryInterceptor,
+			interceptorManager.AuthUnaryInterceptor(verifier),
+			interceptorManager.RateLimitUnaryInterceptor(rateLimiter),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptorManager.RequestIdStreamInterceptor,
+			interceptorManager.RequestLoggerStreamInterceptor,
+			interceptorManager.MetricStreamInterceptor(metrics),
+			interceptorManager.RecoveryStreamInterceptor,
+			interceptorManager.AuthStreamInterceptor(verifier),
+		),
+	)
+	grpcServer := grpc.NewServer(serverOpts...)
+
+	// sections read once at startup, such as the server port or the database, still need a restart
+	configWatcher.Subscribe("logger", func(cfg *config.Config) {
+		if cfg.Logger.Level == "" {
+			return
+		}
+		if err := zapLogger.SetLevel(cfg.Logger.Level); err != nil {
+			zapLogger.Errorf("Failed to apply log level: %v", err)
+		}
+	})
+	configWatcher.Subscribe("rateLimit", func(cfg *config.Config) {
+		rateLimiter.Update(interceptors.RateLimitRules(cfg.RateLimit))
+	})
+	configWatcher.Subscribe("server", interceptorManager.Reload)
+	if err := configWatcher.Start(); err != nil {
+		zapLogger.Errorf("Failed to watch config: %v", err)
+	}
 
-	db, err := mongo.NewMongo(cfg)
+	db, err := mongo.NewMongo(cfg, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("Failed to connect mongo: %v\n", err)
+	}
 
 	databases, err := db.ListDatabaseNames(context.Background(), nil)
 	if err != nil {
@@ -75,13 +205,91 @@ func main() {
 	}
 
 	productRepository := repository.NewProductRepository(db, cfg)
+	unitOfWork := repository.NewUnitOfWork(db, cfg)
 
-	use_case.NewProductUseCase(cfg, productRepository, zapLogger, grpcServer)
+	use_case.NewProductUseCase(cfg, productRepository, unitOfWork, zapLogger, grpcServer)
 
-	zapLogger.Infof("Server started at %v", listen.Addr().String())
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	healthChecker := healthcheck.NewChecker(cfg, zapLogger, db, healthServer)
+	healthChecker.Start()
 
-	err = grpcServer.Serve(listen)
-	if err != nil {
-		zapLogger.Error("ERROR:", err.Error())
+	reflection.Register(grpcServer)
+
+	var webServer *web.Server
+	if cfg.Server.Web.Enabled {
+		webServer, err = web.NewServer(cfg, zapLogger, grpcServer, webTLS)
+		if err != nil {
+			zapLogger.Fatalf("Failed to create web server: %v\n", err)
+		}
+		webServer.Start()
+	}
+
+	go func() {
+		zapLogger.Infof("Server started at %v", listen.Addr().String())
+		if err := grpcServer.Serve(listen); err != nil {
+			zapLogger.Error("ERROR:", err.Error())
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	sig := <-quit
+	zapLogger.Infof("Received %s, shutting down", sig)
+	configWatcher.Stop()
+
+	// stop reporting SERVING first so clients and load balancers move away while the listener still accepts
+	healthChecker.Stop()
+	healthServer.Shutdown()
+	time.Sleep(time.Duration(cfg.Server.DrainPeriod) * time.Second)
+
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
 	}
+
+	stopped := make(chan struct{})
+	go func() {
+		// web requests are served through grpcServer.ServeHTTP, which GracefulStop does not wait for
+		if webServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := webServer.Shutdown(ctx); err != nil {
+				zapLogger.Errorf("Failed to shutdown web server: %v", err)
+			}
+		}
+
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		zapLogger.Warn("Graceful stop timed out, cancelling in-flight RPCs")
+		grpcServer.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.CtxTimeout)*time.Second)
+	defer cancel()
+	if err := db.Disconnect(ctx); err != nil {
+		zapLogger.Errorf("Failed to disconnect mongo: %v", err)
+	}
+
+	if err := metricServer.Shutdown(ctx); err != nil {
+		zapLogger.Errorf("Failed to shutdown metric server: %v", err)
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			zapLogger.Errorf("Failed to shutdown admin server: %v", err)
+		}
+	}
+
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		zapLogger.Errorf("Failed to flush traces: %v", err)
+	}
+
+	zapLogger.Info("Server exited properly")
+	_ = zapLogger.Sync()
 }
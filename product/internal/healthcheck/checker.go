@@ -0,0 +1,75 @@
+package healthcheck
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	pb "github.com/sefikcan/ms-grpc-sample/proto"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"time"
+)
+
+const defaultCheckInterval = 10 * time.Second
+
+// Checker drives the grpc.health.v1 status of the product server from periodic mongo pings
+type Checker struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *mongo.Client
+	server *health.Server
+	done   chan struct{}
+}
+
+func (c *Checker) Start() {
+	c.check()
+
+	interval := time.Duration(c.cfg.Server.HealthCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.check()
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+func (c *Checker) Stop() {
+	close(c.done)
+}
+
+func (c *Checker) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.cfg.Server.CtxTimeout)*time.Second)
+	defer cancel()
+
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if err := c.db.Ping(ctx, readpref.Primary()); err != nil {
+		c.logger.Errorf("Health check mongo ping failed: %v", err)
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	c.server.SetServingStatus("", servingStatus)
+	c.server.SetServingStatus(pb.ProductService_ServiceDesc.ServiceName, servingStatus)
+}
+
+func NewChecker(cfg *config.Config, logger logger.Logger, db *mongo.Client, server *health.Server) *Checker {
+	return &Checker{
+		cfg:    cfg,
+		logger: logger,
+		db:     db,
+		server: server,
+		done:   make(chan struct{}),
+	}
+}
@@ -0,0 +1,103 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/auth"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const productServicePrefix = "/product.ProductService/"
+
+// methodRoles is the minimum role for each ProductService RPC, RPCs missing here need admin
+var methodRoles = map[string]auth.Role{
+	productServicePrefix + "GetProductDetail": auth.RoleViewer,
+	productServicePrefix + "ListProducts":     auth.RoleViewer,
+	productServicePrefix + "CreateProduct":    auth.RoleEditor,
+	productServicePrefix + "UpdateProduct":    auth.RoleEditor,
+	productServicePrefix + "DeleteProduct":    auth.RoleAdmin,
+}
+
+func (im *InterceptorManager) AuthUnaryInterceptor(verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := im.authorize(ctx, verifier, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (im *InterceptorManager) AuthStreamInterceptor(verifier *auth.Verifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := im.authorize(ss.Context(), verifier, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorize authenticates the caller and checks its role for ProductService methods,
+// health and reflection stay open so probes and tooling work without a token.
+func (im *InterceptorManager) authorize(ctx context.Context, verifier *auth.Verifier, method string) (context.Context, error) {
+	if !im.cfg.Auth.Enabled || !strings.HasPrefix(method, productServicePrefix) {
+		return ctx, nil
+	}
+
+	principal, err := im.authenticate(ctx, verifier)
+	if err != nil {
+		im.logger.FromContext(ctx).Warnf("Unauthenticated: %v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid or missing credentials")
+	}
+
+	required, exist := methodRoles[method]
+	if !exist {
+		required = auth.RoleAdmin
+	}
+
+	if !principal.HasRole(required) {
+		im.logger.FromContext(ctx).Warnf("Subject: %s, Required role: %s", principal.Subject, required)
+		return nil, status.Errorf(codes.PermissionDenied, "Role %s is required", required)
+	}
+
+	ctx = logger.WithContext(ctx, im.logger.FromContext(ctx).With(logger.FieldUser, principal.Subject))
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func (im *InterceptorManager) authenticate(ctx context.Context, verifier *auth.Verifier) (*auth.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(auth.MetadataAuthorization); len(values) > 0 {
+		return verifier.VerifyAuthorization(values[0])
+	}
+
+	subjects := md.Get(auth.MetadataPrincipalSubject)
+	if im.cfg.Auth.TrustPrincipalMetadata && hasVerifiedClientCertificate(ctx) && len(subjects) > 0 {
+		principal := &auth.Principal{Subject: subjects[0]}
+		if roles := md.Get(auth.MetadataPrincipalRoles); len(roles) > 0 {
+			principal.Roles = auth.ParseRoles(roles[0])
+		}
+		return principal, nil
+	}
+
+	return nil, auth.ErrMissingToken
+}
+
+func hasVerifiedClientCertificate(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(tlsInfo.State.VerifiedChains) > 0
+}
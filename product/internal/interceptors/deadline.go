@@ -0,0 +1,29 @@
+package interceptors
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// DeadlineUnaryInterceptor bounds calls that arrive without a deadline by Server.CtxTimeout, so mongo
+// operations always run under one, and reports failures caused by the context with its matching code.
+func (im *InterceptorManager) DeadlineUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if _, ok := ctx.Deadline(); !ok && im.ctxTimeout.Load() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(im.ctxTimeout.Load()))
+		defer cancel()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	res, err := handler(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	return res, err
+}
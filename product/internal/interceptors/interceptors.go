@@ -0,0 +1,42 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"google.golang.org/grpc"
+	"sync/atomic"
+	"time"
+)
+
+type InterceptorManager struct {
+	cfg    *config.Config
+	logger logger.Logger
+	// ctxTimeout is Server.CtxTimeout of the latest config as a time.Duration
+	ctxTimeout atomic.Int64
+}
+
+// serverStream lets stream interceptors hand a derived context to the handler
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Reload applies the settings of cfg that may change while the server runs
+func (im *InterceptorManager) Reload(cfg *config.Config) {
+	im.ctxTimeout.Store(int64(time.Duration(cfg.Server.CtxTimeout) * time.Second))
+}
+
+func NewInterceptorManager(cfg *config.Config, logger logger.Logger) *InterceptorManager {
+	im := &InterceptorManager{
+		cfg:    cfg,
+		logger: logger,
+	}
+	im.Reload(cfg)
+
+	return im
+}
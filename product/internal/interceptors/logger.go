@@ -0,0 +1,21 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/util"
+)
+
+// withRequestLogger stores a logger in ctx that adds the request id, trace, tenant and method of the call
+// to every line, interceptors and handlers further down get it with FromContext.
+func (im *InterceptorManager) withRequestLogger(ctx context.Context, method string) context.Context {
+	fields := []interface{}{logger.FieldRequestId, util.GetRequestId(ctx), logger.FieldMethod, method}
+	if traceId := util.GetTraceId(ctx); traceId != "" {
+		fields = append(fields, logger.FieldTraceId, traceId, logger.FieldSpanId, util.GetSpanId(ctx))
+	}
+	if tenant := util.GetTenant(ctx); tenant != "" {
+		fields = append(fields, logger.FieldTenant, tenant)
+	}
+
+	return logger.WithContext(ctx, im.logger.With(fields...))
+}
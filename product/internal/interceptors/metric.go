@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+func (im *InterceptorManager) MetricUnaryInterceptor(metrics metric.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		metrics.IncInFlight(info.FullMethod)
+		defer metrics.DecInFlight(info.FullMethod)
+
+		if message, ok := req.(proto.Message); ok {
+			metrics.ObserveReceivedBytes(info.FullMethod, proto.Size(message))
+		}
+
+		res, err := handler(ctx, req)
+
+		if message, ok := res.(proto.Message); ok && err == nil {
+			metrics.ObserveSentBytes(info.FullMethod, proto.Size(message))
+		}
+
+		code := status.Code(err).String()
+		metrics.IncHits(code, info.FullMethod)
+		metrics.ObserveResponseTime(code, info.FullMethod, time.Since(start).Seconds())
+
+		return res, err
+	}
+}
+
+func (im *InterceptorManager) MetricStreamInterceptor(metrics metric.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		metrics.IncInFlight(info.FullMethod)
+		defer metrics.DecInFlight(info.FullMethod)
+
+		err := handler(srv, &metricServerStream{ServerStream: ss, metrics: metrics, method: info.FullMethod})
+
+		code := status.Code(err).String()
+		metrics.IncHits(code, info.FullMethod)
+		metrics.ObserveResponseTime(code, info.FullMethod, time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// metricServerStream observes the size of every message of a stream
+type metricServerStream struct {
+	grpc.ServerStream
+	metrics metric.Metrics
+	method  string
+}
+
+func (s *metricServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if message, ok := m.(proto.Message); ok && err == nil {
+		s.metrics.ObserveReceivedBytes(s.method, proto.Size(message))
+	}
+
+	return err
+}
+
+func (s *metricServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if message, ok := m.(proto.Message); ok && err == nil {
+		s.metrics.ObserveSentBytes(s.method, proto.Size(message))
+	}
+
+	return err
+}
@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/auth"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/ratelimit"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"math"
+	"net"
+	"strconv"
+)
+
+func (im *InterceptorManager) NewRateLimiter() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(RateLimitRules(im.cfg.RateLimit))
+}
+
+// RateLimitRules returns the limiter rules of cfg, there are none while rate limiting is disabled
+func RateLimitRules(cfg config.RateLimitConfig) (ratelimit.Rule, map[string]ratelimit.Rule) {
+	rules := make(map[string]ratelimit.Rule, len(cfg.Methods))
+	if !cfg.Enabled {
+		return ratelimit.Rule{}, rules
+	}
+
+	for _, method := range cfg.Methods {
+		rules[method.Method] = ratelimit.Rule{Rate: method.Rate, Burst: method.Burst}
+	}
+
+	return ratelimit.Rule{Rate: cfg.Rate, Burst: cfg.Burst}, rules
+}
+
+// RateLimitUnaryInterceptor limits each client per method, it runs after the auth interceptor to key on the principal
+func (im *InterceptorManager) RateLimitUnaryInterceptor(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := clientIdentity(ctx)
+		result := limiter.Allow(info.FullMethod, client)
+		if result.Allowed {
+			return handler(ctx, req)
+		}
+
+		im.logger.FromContext(ctx).Warnf("Client: %s, Rate limited", client)
+
+		retryAfter := strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+		if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+			im.logger.FromContext(ctx).Warnf("Failed to set retry-after header: %v", err)
+		}
+
+		st, err := status.New(codes.ResourceExhausted, "Too many requests").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)})
+		if err != nil {
+			return nil, status.Errorf(codes.ResourceExhausted, "Too many requests")
+		}
+
+		return nil, st.Err()
+	}
+}
+
+func clientIdentity(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return "sub:" + principal.Subject
+	}
+
+	address := util.GetPeerAddress(ctx)
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return "ip:" + host
+	}
+
+	return "ip:" + address
+}
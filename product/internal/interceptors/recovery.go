@@ -0,0 +1,35 @@
+package interceptors
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+)
+
+func (im *InterceptorManager) RecoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = im.recoverPanic(ctx, info.FullMethod, r)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (im *InterceptorManager) RecoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = im.recoverPanic(ss.Context(), info.FullMethod, r)
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+func (im *InterceptorManager) recoverPanic(ctx context.Context, method string, r interface{}) error {
+	im.logger.FromContext(ctx).Errorf("Panic: %v, Stack: %s", r, debug.Stack())
+
+	return status.Errorf(codes.Internal, "Internal Error")
+}
@@ -0,0 +1,41 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/labstack/gommon/random"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func (im *InterceptorManager) RequestIdUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestId := getOrGenerateRequestId(ctx)
+	ctx = im.withRequestLogger(util.WithRequestId(ctx, requestId), info.FullMethod)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(util.HeaderXRequestID, requestId)); err != nil {
+		im.logger.FromContext(ctx).Warnf("Failed to set request id header: %v", err)
+	}
+
+	return handler(ctx, req)
+}
+
+func (im *InterceptorManager) RequestIdStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	requestId := getOrGenerateRequestId(ss.Context())
+	ctx := im.withRequestLogger(util.WithRequestId(ss.Context(), requestId), info.FullMethod)
+	if err := ss.SetHeader(metadata.Pairs(util.HeaderXRequestID, requestId)); err != nil {
+		im.logger.FromContext(ctx).Warnf("Failed to set request id header: %v", err)
+	}
+
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+func getOrGenerateRequestId(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if values := md.Get(util.HeaderXRequestID); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+
+	// same generator echo uses for the BFF request ids
+	return random.String(32)
+}
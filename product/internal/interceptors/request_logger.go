@@ -0,0 +1,28 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (im *InterceptorManager) RequestLoggerUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+
+	im.logger.FromContext(ctx).Infof("Code: %s, Peer: %s, Time: %s", status.Code(err), util.GetPeerAddress(ctx), time.Since(start).String())
+
+	return res, err
+}
+
+func (im *InterceptorManager) RequestLoggerStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+
+	ctx := ss.Context()
+	im.logger.FromContext(ctx).Infof("Code: %s, Peer: %s, Time: %s", status.Code(err), util.GetPeerAddress(ctx), time.Since(start).String())
+
+	return err
+}
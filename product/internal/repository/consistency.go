@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidConsistencyToken = errors.New("invalid consistency token")
+
+type consistencyKey struct{}
+
+// consistency carries the operation time a client has already observed. Reads made with it go to the
+// primary in a causally consistent session, writes made with it record their operation time.
+type consistency struct {
+	operationTime *primitive.Timestamp
+}
+
+// NewConsistencyContext prepares ctx for a mutation whose operation time is returned by ConsistencyToken
+func NewConsistencyContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, &consistency{})
+}
+
+// WithConsistencyToken prepares ctx for a read that must observe the write the token was returned from.
+// An empty token leaves the read free to use the configured read preference.
+func WithConsistencyToken(ctx context.Context, token string) (context.Context, error) {
+	if token == "" {
+		return ctx, nil
+	}
+
+	operationTime, err := decodeConsistencyToken(token)
+	if err != nil {
+		return ctx, err
+	}
+
+	return context.WithValue(ctx, consistencyKey{}, &consistency{operationTime: &operationTime}), nil
+}
+
+// ConsistencyToken returns the token of the last operation made with ctx, empty if there is none
+func ConsistencyToken(ctx context.Context) string {
+	state, ok := ctx.Value(consistencyKey{}).(*consistency)
+	if !ok || state.operationTime == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d.%d", state.operationTime.T, state.operationTime.I)
+}
+
+func decodeConsistencyToken(token string) (primitive.Timestamp, error) {
+	t, i, found := strings.Cut(token, ".")
+	if !found {
+		return primitive.Timestamp{}, ErrInvalidConsistencyToken
+	}
+
+	seconds, err := strconv.ParseUint(t, 10, 32)
+	if err != nil {
+		return primitive.Timestamp{}, ErrInvalidConsistencyToken
+	}
+
+	increment, err := strconv.ParseUint(i, 10, 32)
+	if err != nil {
+		return primitive.Timestamp{}, ErrInvalidConsistencyToken
+	}
+
+	return primitive.Timestamp{T: uint32(seconds), I: uint32(increment)}, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestDecodeConsistencyToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		want    primitive.Timestamp
+		wantErr error
+	}{
+		{name: "valid", token: "1700000000.3", want: primitive.Timestamp{T: 1700000000, I: 3}},
+		{name: "largest values", token: "4294967295.4294967295", want: primitive.Timestamp{T: 4294967295, I: 4294967295}},
+		{name: "zero", token: "0.0", want: primitive.Timestamp{}},
+		{name: "missing separator", token: "1700000000", wantErr: ErrInvalidConsistencyToken},
+		{name: "empty seconds", token: ".3", wantErr: ErrInvalidConsistencyToken},
+		{name: "empty increment", token: "1700000000.", wantErr: ErrInvalidConsistencyToken},
+		{name: "seconds overflow", token: "4294967296.1", wantErr: ErrInvalidConsistencyToken},
+		{name: "increment overflow", token: "1.4294967296", wantErr: ErrInvalidConsistencyToken},
+		{name: "negative", token: "-1.3", wantErr: ErrInvalidConsistencyToken},
+		{name: "extra separator", token: "1.2.3", wantErr: ErrInvalidConsistencyToken},
+		{name: "not a number", token: "abc.def", wantErr: ErrInvalidConsistencyToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeConsistencyToken(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("timestamp = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithConsistencyToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		wantToken string
+		wantErr   error
+	}{
+		{name: "empty token leaves ctx alone", token: ""},
+		{name: "valid token round trips", token: "1700000000.3", wantToken: "1700000000.3"},
+		{name: "invalid token", token: "1700000000", wantErr: ErrInvalidConsistencyToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := WithConsistencyToken(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := ConsistencyToken(ctx); got != tt.wantToken {
+				t.Errorf("ConsistencyToken = %q, want %q", got, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestConsistencyToken(t *testing.T) {
+	if got := ConsistencyToken(context.Background()); got != "" {
+		t.Errorf("token without consistency = %q, want empty", got)
+	}
+
+	ctx := NewConsistencyContext(context.Background())
+	if got := ConsistencyToken(ctx); got != "" {
+		t.Errorf("token before a write = %q, want empty", got)
+	}
+
+	ctx.Value(consistencyKey{}).(*consistency).operationTime = &primitive.Timestamp{T: 1700000000, I: 7}
+	if got := ConsistencyToken(ctx); got != "1700000000.7" {
+		t.Errorf("token after a write = %q, want %q", got, "1700000000.7")
+	}
+}
@@ -2,18 +2,35 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"github.com/sefikcan/ms-grpc-sample/product/internal/entity"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"regexp"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
+// ProductRepository methods run inside the ambient transaction when ctx comes from UnitOfWork.WithTransaction
 type ProductRepository interface {
 	Create(ctx context.Context, product entity.Product) (entity.Product, error)
 	Update(ctx context.Context, product entity.Product) (entity.Product, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	GetById(ctx context.Context, id primitive.ObjectID) (entity.Product, error)
+	List(ctx context.Context, filter ProductFilter) ([]entity.Product, error)
+}
+
+// ProductFilter pages through products in id order, After is the last id of the previous page.
+// Query searches names containing it, ignoring case.
+type ProductFilter struct {
+	Category string
+	Query    string
+	After    primitive.ObjectID
+	Limit    int64
 }
 
 type productRepository struct {
@@ -21,26 +38,87 @@ type productRepository struct {
 	config *config.Config
 }
 
-func (p productRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	collection := p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName)
+func (p productRepository) collection() *mongo.Collection {
+	return p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName)
+}
+
+// readCollection follows the client read preference unless the read must see a previous write
+// or runs in a transaction, both of which need the primary.
+func (p productRepository) readCollection(ctx context.Context) *mongo.Collection {
+	state, ok := ctx.Value(consistencyKey{}).(*consistency)
+	if (ok && state.operationTime != nil) || ctx.Value(transactionKey{}) != nil {
+		opts := options.Collection().SetReadPreference(readpref.Primary())
+		return p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName, opts)
+	}
+
+	return p.collection()
+}
+
+// causal runs fn in a causally consistent session when ctx carries consistency state
+// and records the operation time of the session for ConsistencyToken.
+func (p productRepository) causal(ctx context.Context, fn func(ctx context.Context) error) error {
+	state, ok := ctx.Value(consistencyKey{}).(*consistency)
+	if !ok {
+		return fn(ctx)
+	}
 
-	filter := bson.M{"_id": id}
+	session := mongo.SessionFromContext(ctx)
+	if session == nil {
+		var err error
+		session, err = p.db.StartSession(options.Session().SetCausalConsistency(true))
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(ctx)
+
+		if state.operationTime != nil {
+			if err := session.AdvanceOperationTime(state.operationTime); err != nil {
+				return err
+			}
+		}
+		ctx = mongo.NewSessionContext(ctx, session)
+	}
+
+	err := fn(ctx)
+	if operationTime := session.OperationTime(); operationTime != nil {
+		state.operationTime = operationTime
+	}
 
-	_, err := collection.DeleteOne(ctx, filter)
 	return err
 }
 
+func (p productRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return p.causal(ctx, func(ctx context.Context) error {
+		filter := bson.M{"_id": id}
+
+		res, err := p.collection().DeleteOne(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		if res.DeletedCount == 0 {
+			return ErrProductNotFound
+		}
+
+		return nil
+	})
+}
+
 func (p productRepository) Create(ctx context.Context, product entity.Product) (entity.Product, error) {
-	collection := p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName)
+	err := p.causal(ctx, func(ctx context.Context) error {
+		id, err := p.collection().InsertOne(ctx, product)
+		if err != nil {
+			return err
+		}
+
+		product.Id = id.InsertedID.(primitive.ObjectID)
+		return nil
+	})
 
-	id, err := collection.InsertOne(ctx, product)
-	product.Id = id.InsertedID.(primitive.ObjectID)
 	return product, err
 }
 
 func (p productRepository) Update(ctx context.Context, product entity.Product) (entity.Product, error) {
-	collection := p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName)
-
 	filter := bson.M{
 		"_id": product.Id,
 	}
@@ -49,16 +127,30 @@ func (p productRepository) Update(ctx context.Context, product entity.Product) (
 			"name":       product.Name,
 			"category":   product.Category,
 			"optionName": product.OptionName}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	_, err := collection.UpdateOne(ctx, filter, update)
-	return product, err
+	var updatedProduct entity.Product
+	err := p.causal(ctx, func(ctx context.Context) error {
+		return p.collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedProduct)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return entity.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return entity.Product{}, err
+	}
+
+	return updatedProduct, nil
 }
 
 func (p productRepository) GetById(ctx context.Context, id primitive.ObjectID) (entity.Product, error) {
-	collection := p.db.Database(p.config.Mongo.DatabaseName).Collection(p.config.Mongo.CollectionName)
-
 	var product entity.Product
-	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	err := p.causal(ctx, func(ctx context.Context) error {
+		return p.readCollection(ctx).FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return entity.Product{}, ErrProductNotFound
+	}
 	if err != nil {
 		return entity.Product{}, err
 	}
@@ -66,6 +158,35 @@ func (p productRepository) GetById(ctx context.Context, id primitive.ObjectID) (
 	return product, nil
 }
 
+func (p productRepository) List(ctx context.Context, filter ProductFilter) ([]entity.Product, error) {
+	query := bson.M{}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.Query != "" {
+		query["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Query), "$options": "i"}
+	}
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$gt": filter.After}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(filter.Limit)
+
+	products := make([]entity.Product, 0)
+	err := p.causal(ctx, func(ctx context.Context) error {
+		cursor, err := p.readCollection(ctx).Find(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+
+		return cursor.All(ctx, &products)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
 func NewProductRepository(db *mongo.Client, config *config.Config) ProductRepository {
 	return &productRepository{
 		db:     db,
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork groups several repository calls so that they commit or roll back together.
+// Repository methods join the transaction through the txCtx passed to fn, e.g.
+//
+//	err := unitOfWork.WithTransaction(ctx, func(txCtx context.Context) error {
+//		product, err := productRepository.GetById(txCtx, id)
+//		if err != nil {
+//			return err
+//		}
+//		product.Category = category
+//		_, err = productRepository.Update(txCtx, product)
+//		return err
+//	})
+type UnitOfWork interface {
+	WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error
+}
+
+type transactionKey struct{}
+
+type mongoUnitOfWork struct {
+	db *mongo.Client
+}
+
+func (u mongoUnitOfWork) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	// Nested calls join the transaction that is already running on the context
+	if ctx.Value(transactionKey{}) != nil {
+		return fn(ctx)
+	}
+
+	session, err := u.db.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(context.WithValue(sessionCtx, transactionKey{}, true))
+	})
+
+	// writes become visible when the transaction commits, a consistency token has to wait for the commit
+	if state, ok := ctx.Value(consistencyKey{}).(*consistency); ok && err == nil {
+		if operationTime := session.OperationTime(); operationTime != nil {
+			state.operationTime = operationTime
+		}
+	}
+
+	return err
+}
+
+// noopUnitOfWork runs fn without a transaction, standalone mongo servers do not support them.
+type noopUnitOfWork struct{}
+
+func (n noopUnitOfWork) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return fn(ctx)
+}
+
+func NewUnitOfWork(db *mongo.Client, config *config.Config) UnitOfWork {
+	if config.Mongo.ReplicaSet == "" {
+		return &noopUnitOfWork{}
+	}
+
+	return &mongoUnitOfWork{
+		db: db,
+	}
+}
@@ -2,6 +2,7 @@ package use_case
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/sefikcan/ms-grpc-sample/product/internal/entity"
 	"github.com/sefikcan/ms-grpc-sample/product/internal/mappers"
@@ -13,12 +14,18 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"log"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ProductUseCase runs every mutation in unitOfWork, writes added to one later commit or roll back together with it
 type ProductUseCase struct {
 	cfg               *config.Config
 	productRepository repository.ProductRepository
+	unitOfWork        repository.UnitOfWork
 	logger            logger.Logger
 	pb.UnimplementedProductServiceServer
 }
@@ -30,7 +37,13 @@ func (p ProductUseCase) Create(ctx context.Context, request *pb.CreateProductReq
 		OptionName: request.OptionName,
 	}
 
-	res, err := p.productRepository.Create(ctx, product)
+	ctx = repository.NewConsistencyContext(ctx)
+	var res entity.Product
+	err := p.unitOfWork.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		res, err = p.productRepository.Create(txCtx, product)
+		return err
+	})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -39,10 +52,11 @@ func (p ProductUseCase) Create(ctx context.Context, request *pb.CreateProductReq
 	}
 
 	return &pb.CreateProductResponse{
-		Name:       res.Name,
-		Category:   res.Category,
-		OptionName: res.OptionName,
-		Id:         res.Id.Hex(),
+		Name:             res.Name,
+		Category:         res.Category,
+		OptionName:       res.OptionName,
+		Id:               res.Id.Hex(),
+		ConsistencyToken: repository.ConsistencyToken(ctx),
 	}, nil
 }
 
@@ -55,7 +69,21 @@ func (p ProductUseCase) GetById(ctx context.Context, request *pb.GetProductDetai
 		)
 	}
 
+	ctx, err = repository.WithConsistencyToken(ctx, request.ConsistencyToken)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"Cannot parse consistency token",
+		)
+	}
+
 	res, err := p.productRepository.GetById(ctx, oid)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return nil, status.Errorf(
+			codes.NotFound,
+			"Product not found",
+		)
+	}
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -75,7 +103,16 @@ func (p ProductUseCase) Delete(ctx context.Context, request *pb.DeleteProductReq
 		)
 	}
 
-	err = p.productRepository.Delete(ctx, oid)
+	ctx = repository.NewConsistencyContext(ctx)
+	err = p.unitOfWork.WithTransaction(ctx, func(txCtx context.Context) error {
+		return p.productRepository.Delete(txCtx, oid)
+	})
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return nil, status.Errorf(
+			codes.NotFound,
+			"Product not found",
+		)
+	}
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -83,7 +120,9 @@ func (p ProductUseCase) Delete(ctx context.Context, request *pb.DeleteProductReq
 		)
 	}
 
-	return &pb.DeleteProductResponse{}, nil
+	return &pb.DeleteProductResponse{
+		ConsistencyToken: repository.ConsistencyToken(ctx),
+	}, nil
 }
 
 func (p ProductUseCase) Update(ctx context.Context, request *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
@@ -102,7 +141,19 @@ func (p ProductUseCase) Update(ctx context.Context, request *pb.UpdateProductReq
 		Category:   request.Category,
 	}
 
-	res, err := p.productRepository.Update(ctx, product)
+	ctx = repository.NewConsistencyContext(ctx)
+	var res entity.Product
+	err = p.unitOfWork.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		res, err = p.productRepository.Update(txCtx, product)
+		return err
+	})
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return nil, status.Errorf(
+			codes.NotFound,
+			"Product not found",
+		)
+	}
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -111,23 +162,80 @@ func (p ProductUseCase) Update(ctx context.Context, request *pb.UpdateProductReq
 	}
 
 	return &pb.UpdateProductResponse{
-		Name:       res.Name,
-		Category:   res.Category,
-		OptionName: res.OptionName,
-		Id:         res.Id.Hex(),
+		Name:             res.Name,
+		Category:         res.Category,
+		OptionName:       res.OptionName,
+		Id:               res.Id.Hex(),
+		ConsistencyToken: repository.ConsistencyToken(ctx),
 	}, nil
 }
 
+func (p ProductUseCase) List(ctx context.Context, request *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	filter := repository.ProductFilter{
+		Category: request.Category,
+		Query:    request.Query,
+		Limit:    int64(pageSize),
+	}
+
+	if request.PageToken != "" {
+		after, err := primitive.ObjectIDFromHex(request.PageToken)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.InvalidArgument,
+				"Cannot parse page token",
+			)
+		}
+		filter.After = after
+	}
+
+	ctx, err := repository.WithConsistencyToken(ctx, request.ConsistencyToken)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"Cannot parse consistency token",
+		)
+	}
+
+	res, err := p.productRepository.List(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal,
+			fmt.Sprintf("Internal Error: %v\n", err),
+		)
+	}
+
+	response := &pb.ListProductsResponse{
+		Products: make([]*pb.GetProductDetailResponse, 0, len(res)),
+	}
+	for _, product := range res {
+		response.Products = append(response.Products, mappers.DocumentToProduct(product))
+	}
+	if len(res) == int(pageSize) {
+		response.NextPageToken = res[len(res)-1].Id.Hex()
+	}
+
+	return response, nil
+}
+
 type ProductServerStruct struct {
 	pb.UnimplementedProductServiceServer
 	productUseCase *ProductUseCase
 }
 
-func NewProductUseCase(cfg *config.Config, productRepository repository.ProductRepository, logger logger.Logger, grpcServer *grpc.Server) *ProductUseCase {
+func NewProductUseCase(cfg *config.Config, productRepository repository.ProductRepository, unitOfWork repository.UnitOfWork, logger logger.Logger, grpcServer *grpc.Server) *ProductUseCase {
 	productGrpc := &ProductServerStruct{
 		productUseCase: &ProductUseCase{
 			cfg:               cfg,
 			productRepository: productRepository,
+			unitOfWork:        unitOfWork,
 			logger:            logger,
 		},
 	}
@@ -138,7 +246,7 @@ func NewProductUseCase(cfg *config.Config, productRepository repository.ProductR
 func (s *ProductServerStruct) CreateProduct(ctx context.Context, in *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
 	createdProduct, err := s.productUseCase.Create(ctx, in)
 	if err != nil {
-		log.Printf("Failed to create product: %v\n", err)
+		s.productUseCase.logger.FromContext(ctx).Errorf("Failed to create product: %v", err)
 		return nil, err
 	}
 
@@ -148,7 +256,7 @@ func (s *ProductServerStruct) CreateProduct(ctx context.Context, in *pb.CreatePr
 func (s *ProductServerStruct) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
 	updatedProduct, err := s.productUseCase.Update(ctx, in)
 	if err != nil {
-		log.Printf("Failed to update product: %v\n", err)
+		s.productUseCase.logger.FromContext(ctx).Errorf("Failed to update product: %v", err)
 		return nil, err
 	}
 
@@ -158,7 +266,7 @@ func (s *ProductServerStruct) UpdateProduct(ctx context.Context, in *pb.UpdatePr
 func (s *ProductServerStruct) DeleteProduct(ctx context.Context, in *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
 	deletedProduct, err := s.productUseCase.Delete(ctx, in)
 	if err != nil {
-		log.Printf("Failed to delete product: %v\n", err)
+		s.productUseCase.logger.FromContext(ctx).Errorf("Failed to delete product: %v", err)
 		return nil, err
 	}
 
@@ -168,9 +276,19 @@ func (s *ProductServerStruct) DeleteProduct(ctx context.Context, in *pb.DeletePr
 func (s *ProductServerStruct) GetProductDetail(ctx context.Context, in *pb.GetProductDetailRequest) (*pb.GetProductDetailResponse, error) {
 	product, err := s.productUseCase.GetById(ctx, in)
 	if err != nil {
-		log.Printf("Failed to get product: %v\n", err)
+		s.productUseCase.logger.FromContext(ctx).Errorf("Failed to get product: %v", err)
 		return nil, err
 	}
 
 	return product, nil
 }
+
+func (s *ProductServerStruct) ListProducts(ctx context.Context, in *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	products, err := s.productUseCase.List(ctx, in)
+	if err != nil {
+		s.productUseCase.logger.FromContext(ctx).Errorf("Failed to list products: %v", err)
+		return nil, err
+	}
+
+	return products, nil
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost}
+	corsAllowedHeaders = []string{
+		"Authorization",
+		"Content-Type",
+		"Connect-Protocol-Version",
+		"Connect-Timeout-Ms",
+		"Grpc-Timeout",
+		"X-Grpc-Web",
+		"X-User-Agent",
+		"X-Request-Id",
+	}
+	corsExposedHeaders = []string{
+		"Grpc-Status",
+		"Grpc-Message",
+		"Grpc-Status-Details-Bin",
+		"X-Request-Id",
+		"Retry-After",
+	}
+)
+
+// newCorsHandler answers preflight requests and marks responses to allowed origins as readable by the browser
+func newCorsHandler(allowedOrigins []string, next http.Handler) http.Handler {
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[origin] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(origins["*"] || origins[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+			header.Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			header.Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
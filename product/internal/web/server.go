@@ -0,0 +1,68 @@
+package web
+
+import (
+	"connectrpc.com/vanguard/vanguardgrpc"
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+)
+
+// Server transcodes gRPC-Web and Connect requests, binary or JSON over HTTP/1.1 and HTTP/2, into calls on the
+// grpc server, so they run through the same interceptors as native gRPC calls.
+type Server struct {
+	cfg        *config.Config
+	logger     logger.Logger
+	httpServer *http.Server
+}
+
+func (s *Server) Start() {
+	go func() {
+		s.logger.Infof("Web server is listening on PORT: %s", s.cfg.Server.Web.Port)
+
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Fatalf("Error starting web server: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops accepting requests and waits for in-flight ones until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// NewServer has to be called after every service is registered on grpcServer, tlsConfig is nil for plaintext
+// in which case HTTP/2 is served without TLS, otherwise it has to offer h2 and http/1.1 in ALPN.
+func NewServer(cfg *config.Config, logger logger.Logger, grpcServer *grpc.Server, tlsConfig *tls.Config) (*Server, error) {
+	transcoder, err := vanguardgrpc.NewTranscoder(grpcServer)
+	if err != nil {
+		return nil, err
+	}
+
+	var handler http.Handler = newCorsHandler(cfg.Server.Web.AllowedOrigins, transcoder)
+	if tlsConfig == nil {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	return &Server{
+		cfg:    cfg,
+		logger: logger,
+		httpServer: &http.Server{
+			Addr:      net.JoinHostPort(cfg.Server.Host, cfg.Server.Web.Port),
+			Handler:   handler,
+			TLSConfig: tlsConfig,
+		},
+	}, nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// roleRanks orders the roles, a role grants everything the lower ranked ones do
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Metadata keys the verified principal travels with between services
+const (
+	MetadataAuthorization    = "authorization"
+	MetadataPrincipalSubject = "x-principal-subject"
+	MetadataPrincipalRoles   = "x-principal-roles"
+)
+
+type Principal struct {
+	Subject string
+	Roles   []Role
+}
+
+func (p *Principal) HasRole(required Role) bool {
+	for _, role := range p.Roles {
+		if roleRanks[role] >= roleRanks[required] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Principal) RolesString() string {
+	roles := make([]string, 0, len(p.Roles))
+	for _, role := range p.Roles {
+		roles = append(roles, string(role))
+	}
+
+	return strings.Join(roles, ",")
+}
+
+func ParseRoles(value string) []Role {
+	var roles []Role
+	for _, role := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		roles = append(roles, Role(role))
+	}
+
+	return roles
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
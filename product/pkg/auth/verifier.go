@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"os"
+	"strings"
+)
+
+const defaultRolesClaim = "roles"
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrNoKey        = errors.New("no key configured for the token")
+)
+
+// Verifier validates JWTs signed with the configured HMAC secret, RSA public key or local JWKS keys
+type Verifier struct {
+	cfg        config.AuthConfig
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	jwks       map[string]interface{}
+	parser     *jwt.Parser
+}
+
+func (v *Verifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Subject: subject,
+		Roles:   v.roles(claims),
+	}, nil
+}
+
+// VerifyAuthorization verifies the token of an "Bearer <token>" authorization value
+func (v *Verifier) VerifyAuthorization(authorization string) (*Principal, error) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrMissingToken
+	}
+
+	return v.Verify(token)
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if key, exist := v.jwks[kid]; exist {
+			return key, nil
+		}
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if v.hmacSecret != nil {
+			return v.hmacSecret, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+	}
+
+	return nil, ErrNoKey
+}
+
+// roles reads the roles claim either as a list or as a comma or space separated string
+func (v *Verifier) roles(claims jwt.MapClaims) []Role {
+	rolesClaim := v.cfg.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+
+	switch value := claims[rolesClaim].(type) {
+	case string:
+		return ParseRoles(value)
+	case []interface{}:
+		roles := make([]Role, 0, len(value))
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				roles = append(roles, Role(s))
+			}
+		}
+		return roles
+	}
+
+	return nil
+}
+
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	verifier := &Verifier{
+		cfg: cfg,
+	}
+
+	if cfg.HMACSecret != "" {
+		verifier.hmacSecret = []byte(cfg.HMACSecret)
+	}
+
+	if cfg.RSAPublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.RSAPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		verifier.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.RSAPublicKeyFile, err)
+		}
+	}
+
+	if cfg.JWKSFile != "" {
+		jwks, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.JWKSFile, err)
+		}
+		verifier.jwks = jwks
+	}
+
+	if verifier.hmacSecret == nil && verifier.rsaKey == nil && len(verifier.jwks) == 0 {
+		return nil, errors.New("auth is enabled but no hmacSecret, rsaPublicKeyFile or jwksFile is configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	verifier.parser = jwt.NewParser(opts...)
+
+	return verifier, nil
+}
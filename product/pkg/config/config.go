@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"os"
 	"reflect"
@@ -9,19 +11,22 @@ import (
 )
 
 const (
-	defaultConfigPath     = "product/pkg/config/"
-	tagName               = "mapstructure"
-	configFileType        = "yaml"
-	defaultConfigFileName = "config-dev"
-	environmentKey        = "environment"
+	defaultConfigPath  = "product/pkg/config/"
+	tagName            = "mapstructure"
+	configFileType     = "yaml"
+	baseConfigFileName = "config"
+	environmentKey     = "environment"
 )
 
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	Mongo  MongoConfig  `mapstructure:"mongo"`
-	Metric MetricConfig `mapstructure:"metric"`
-	Logger LoggerConfig `mapstructure:"logger"`
-	Jaeger JaegerConfig `mapstructure:"jaeger"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Mongo     MongoConfig     `mapstructure:"mongo"`
+	Metric    MetricConfig    `mapstructure:"metric"`
+	Logger    LoggerConfig    `mapstructure:"logger"`
+	Jaeger    JaegerConfig    `mapstructure:"jaeger"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	RateLimit RateLimitConfig `mapstructure:"rateLimit"`
+	Admin     AdminConfig     `mapstructure:"admin"`
 }
 
 type ServerConfig struct {
@@ -31,13 +36,99 @@ type ServerConfig struct {
 	Mode        string `mapstructure:"mode"`
 	NetworkType string `mapstructure:"networkType"`
 	CtxTimeout  int    `mapstructure:"ctxTimeout"`
+	// HealthCheckInterval in seconds between mongo pings that drive the grpc health status
+	HealthCheckInterval int `mapstructure:"healthCheckInterval"`
+	// DrainPeriod in seconds between reporting NOT_SERVING and refusing new RPCs on shutdown
+	DrainPeriod int `mapstructure:"drainPeriod"`
+	// ShutdownTimeout in seconds to wait for in-flight RPCs before they are cancelled
+	ShutdownTimeout int       `mapstructure:"shutdownTimeout"`
+	TLS             TLSConfig `mapstructure:"tls"`
+	Web             WebConfig `mapstructure:"web"`
+}
+
+// WebConfig serves the gRPC services to browsers over gRPC-Web and Connect on a second port,
+// native gRPC is accepted there too
+type WebConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Port    string `mapstructure:"port"`
+	// AllowedOrigins are the CORS origins browsers may call from, * allows any
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+	// ClientAuth replaces server.tls.clientAuth on this port, browsers rarely hold client certificates
+	ClientAuth string `mapstructure:"clientAuth"`
+}
+
+type TLSConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	CertFile   string `mapstructure:"certFile"`
+	KeyFile    string `mapstructure:"keyFile"`
+	CAFile     string `mapstructure:"caFile"`
+	ServerName string `mapstructure:"serverName"`
+	// ClientAuth is used by servers: none, request, require, verifyIfGiven or requireAndVerify
+	ClientAuth string `mapstructure:"clientAuth"`
 }
 
 type LoggerConfig struct {
-	Development      bool   `mapstructure:"development"`
-	Encoding         string `mapstructure:"encoding"`
-	Level            string `mapstructure:"level"`
-	ElasticSearchUrl string `mapstructure:"elasticSearchUrl"`
+	Development      bool             `mapstructure:"development"`
+	Encoding         string           `mapstructure:"encoding"`
+	Level            string           `mapstructure:"level"`
+	ElasticSearchUrl string           `mapstructure:"elasticSearchUrl"`
+	Elastic          ElasticLogConfig `mapstructure:"elastic"`
+	// Sinks receive every entry at or above their own level, stderr with Level and Encoding is used when there are none
+	Sinks    []LogSinkConfig   `mapstructure:"sinks"`
+	Sampling LogSamplingConfig `mapstructure:"sampling"`
+}
+
+// LogSinkConfig is a log destination: stdout, stderr, file, elasticsearch or syslog.
+// Level and Encoding fall back to the logger ones, Encoding is json or console.
+type LogSinkConfig struct {
+	Type     string            `mapstructure:"type"`
+	Level    string            `mapstructure:"level"`
+	Encoding string            `mapstructure:"encoding"`
+	File     FileLogSinkConfig `mapstructure:"file"`
+	Syslog   SyslogSinkConfig  `mapstructure:"syslog"`
+}
+
+// FileLogSinkConfig rotates Path once it reaches MaxSize megabytes, rotated files are removed
+// after MaxAge days or when there are more than MaxBackups of them, zero keeps them.
+type FileLogSinkConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxSize    int    `mapstructure:"maxSize"`
+	MaxAge     int    `mapstructure:"maxAge"`
+	MaxBackups int    `mapstructure:"maxBackups"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// SyslogSinkConfig writes to the local syslog daemon when Network and Address are empty
+type SyslogSinkConfig struct {
+	Network  string `mapstructure:"network"`
+	Address  string `mapstructure:"address"`
+	Tag      string `mapstructure:"tag"`
+	Facility string `mapstructure:"facility"`
+}
+
+// LogSamplingConfig logs the first Initial entries with the same level and message every Tick milliseconds
+// and every Thereafter-th one after that.
+type LogSamplingConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	Tick       int  `mapstructure:"tick"`
+	Initial    int  `mapstructure:"initial"`
+	Thereafter int  `mapstructure:"thereafter"`
+}
+
+// ElasticLogConfig configures the elasticsearch sink, entries are shipped to ElasticSearchUrl
+// in bulk requests from a bounded queue
+type ElasticLogConfig struct {
+	// Index is the index or, with DataStream, the data stream the entries are written to
+	Index      string `mapstructure:"index"`
+	DataStream bool   `mapstructure:"dataStream"`
+	BatchSize  int    `mapstructure:"batchSize"`
+	// FlushInterval in milliseconds, a batch is sent when it is full or the interval elapsed
+	FlushInterval int `mapstructure:"flushInterval"`
+	QueueSize     int `mapstructure:"queueSize"`
+	// QueuePolicy is drop or block, it decides what logging does while the queue is full
+	QueuePolicy string `mapstructure:"queuePolicy"`
+	// MaxRetries of a failed bulk request before its entries are dropped, 3 when unset
+	MaxRetries int `mapstructure:"maxRetries"`
 }
 
 type MongoConfig struct {
@@ -47,6 +138,14 @@ type MongoConfig struct {
 	Port           string `mapstructure:"port"`
 	DatabaseName   string `mapstructure:"databaseName"`
 	CollectionName string `mapstructure:"collectionName"`
+	ReplicaSet     string `mapstructure:"replicaSet"`
+	// SlowQueryThreshold in milliseconds, commands taking longer are logged. 0 disables the log
+	SlowQueryThreshold int `mapstructure:"slowQueryThreshold"`
+	// ReadPreference for product queries: primary, primaryPreferred, secondary, secondaryPreferred or nearest
+	ReadPreference string `mapstructure:"readPreference"`
+	// MaxStaleness in seconds a secondary may lag behind before reads skip it, 0 disables the limit.
+	// It is at least 90 and needs a read preference other than primary
+	MaxStaleness int `mapstructure:"maxStaleness"`
 }
 
 type MetricConfig struct {
@@ -54,13 +153,63 @@ type MetricConfig struct {
 	ServiceName string `mapstructure:"serviceName"`
 }
 
+type AuthConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	Issuer           string `mapstructure:"issuer"`
+	Audience         string `mapstructure:"audience"`
+	HMACSecret       string `mapstructure:"hmacSecret"`
+	RSAPublicKeyFile string `mapstructure:"rsaPublicKeyFile"`
+	JWKSFile         string `mapstructure:"jwksFile"`
+	// RolesClaim names the claim holding viewer, editor or admin roles, roles by default
+	RolesClaim string `mapstructure:"rolesClaim"`
+	// TrustPrincipalMetadata accepts the principal metadata forwarded by the BFF without a token,
+	// only from peers that presented a verified client certificate
+	TrustPrincipalMetadata bool `mapstructure:"trustPrincipalMetadata"`
+}
+
+// AdminConfig is the listener for runtime operations, keep Host off public interfaces.
+// Callers need Token as bearer token or, with auth enabled, a token with the admin role.
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    string `mapstructure:"port"`
+	Token   string `mapstructure:"token"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Rate is the calls per second each client may make and Burst how many it may make at once
+	Rate    float64                 `mapstructure:"rate"`
+	Burst   int                     `mapstructure:"burst"`
+	Methods []MethodRateLimitConfig `mapstructure:"methods"`
+}
+
+type MethodRateLimitConfig struct {
+	// Method is the full gRPC method name, e.g. /product.ProductService/CreateProduct
+	Method string  `mapstructure:"method"`
+	Rate   float64 `mapstructure:"rate"`
+	Burst  int     `mapstructure:"burst"`
+}
+
+// JaegerConfig configures tracing, spans go to the Jaeger agent on Host or to an OTLP collector
 type JaegerConfig struct {
 	Host        string `mapstructure:"host"`
 	ServiceName string `mapstructure:"serviceName"`
-	LogSpans    bool   `mapstructure:"logSpans"`
+	// LogSpans logs every finished span at debug level
+	LogSpans bool `mapstructure:"logSpans"`
+	// Exporter is jaeger, otlp or none
+	Exporter string `mapstructure:"exporter"`
+	// OtlpEndpoint is the host:port of the OTLP gRPC collector, OtlpInsecure disables TLS towards it
+	OtlpEndpoint string `mapstructure:"otlpEndpoint"`
+	OtlpInsecure bool   `mapstructure:"otlpInsecure"`
+	// Sampler is always, never or ratio, the decision of the caller is kept when there is one
+	Sampler      string  `mapstructure:"sampler"`
+	SamplerRatio float64 `mapstructure:"samplerRatio"`
 }
 
-func NewConfig() *Config {
+// NewConfig reads the config of the environment, it terminates listing every problem when the config is unusable.
+// In REMOTE mode the config server document it was read from is returned as well, the Watcher starts from it.
+func NewConfig() (*Config, []byte) {
 	env, _ := os.LookupEnv(environmentKey)
 	fmt.Println("Environment: [" + env + "] read from runtime arguments [" + environmentKey + "].")
 
@@ -93,45 +242,31 @@ func getAllKeys(t reflect.Type) []string {
 	return result
 }
 
-var readFromEnv = func(v *viper.Viper) *viper.Viper {
-	fmt.Println("Reading environment configuration")
-	addKeysToViper(v)
-	v.AutomaticEnv()
-	return v
-}
-
-var readFromConfigServer = func(v *viper.Viper) *viper.Viper {
-	//TODO: Implement config server
-	return v
-}
-
-var readFromAppYml = func(v *viper.Viper) *viper.Viper {
-	fmt.Println("Reading application yml configuration")
-	v.SetConfigName(defaultConfigFileName)
-	v.SetTypeByDefaultValue(true)
-	v.SetConfigType(configFileType)
-	v.AddConfigPath("./" + defaultConfigPath)
-	if err := v.ReadInConfig(); err != nil {
-		fmt.Printf("Viper read config has an error; %e\n", err)
+var ReadConfig = func(c *Config, env string) (*Config, []byte) {
+	fmt.Println("Configuration read initiated...")
+	var remote []byte
+	l, err := newLoader(env, os.Args[1:])
+	if err == nil {
+		remote, err = l.load(c, nil)
+	}
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		exitWithProblems(err)
 	}
 
-	return v
+	return c, remote
 }
 
-var ReadConfig = func(c *Config, env string) *Config {
-	fmt.Println("Configuration read initiated...")
-	v := viper.New()
-	switch {
-	case env == "DEV":
-		v = readFromAppYml(v)
-	case env == "REMOTE":
-		v = readFromConfigServer(v)
-	default:
-		v = readFromEnv(v)
+// exitWithProblems prints every problem on its own line, a panic would bury them under a stack trace
+func exitWithProblems(err error) {
+	fmt.Fprintln(os.Stderr, "Configuration is invalid, terminating:")
+	for _, problem := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintln(os.Stderr, "  - "+problem)
 	}
-	if err := v.Unmarshal(&c); err != nil {
-		panic(any("Configuration unmarshalling occurred an error, terminating: " + err.Error()))
-	}
-
-	return c
+	os.Exit(1)
 }
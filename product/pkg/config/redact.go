@@ -0,0 +1,74 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are parts of config keys whose values are never shown
+var secretKeys = []string{"password", "secret", "token", "credential"}
+
+// Redacted returns cfg keyed like the yaml files with secrets and url credentials replaced
+func Redacted(cfg *Config) map[string]interface{} {
+	return redactStruct(reflect.ValueOf(cfg).Elem())
+}
+
+func redactStruct(v reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get(tagName)
+		result[key] = redactValue(key, v.Field(i))
+	}
+
+	return result
+}
+
+func redactValue(key string, v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Slice:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = redactValue(key, v.Index(i))
+		}
+		return values
+	case reflect.String:
+		if v.String() == "" {
+			return ""
+		}
+		if isSecretKey(key) {
+			return redacted
+		}
+		return redactUrl(v.String())
+	default:
+		return v.Interface()
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactUrl(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+
+	return strings.Replace(value, u.User.String()+"@", redacted+"@", 1)
+}
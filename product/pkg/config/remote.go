@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The config server is configured from the environment, the config it serves is not loaded yet
+const (
+	configServerTypeKey        = "CONFIG_SERVER_TYPE"
+	configServerUrlKey         = "CONFIG_SERVER_URL"
+	configServerApplicationKey = "CONFIG_SERVER_APPLICATION"
+	configServerProfileKey     = "CONFIG_SERVER_PROFILE"
+	configServerLabelKey       = "CONFIG_SERVER_LABEL"
+	configServerKeyKey         = "CONFIG_SERVER_KEY"
+	configServerUsernameKey    = "CONFIG_SERVER_USERNAME"
+	configServerPasswordKey    = "CONFIG_SERVER_PASSWORD"
+	configServerTokenKey       = "CONFIG_SERVER_TOKEN"
+	configServerRetriesKey     = "CONFIG_SERVER_RETRIES"
+	configServerBackoffKey     = "CONFIG_SERVER_BACKOFF"
+	configServerTimeoutKey     = "CONFIG_SERVER_TIMEOUT"
+	configCacheFileKey         = "CONFIG_CACHE_FILE"
+
+	ConfigServerSpring = "spring"
+	ConfigServerConsul = "consul"
+	ConfigServerEtcd   = "etcd"
+	ConfigServerFile   = "file"
+
+	defaultApplicationName     = "product"
+	defaultProfile             = "default"
+	defaultConfigRetries       = 5
+	defaultConfigBackoff       = 500 * time.Millisecond
+	defaultConfigMaxBackoff    = 10 * time.Second
+	defaultConfigServerTimeout = 5 * time.Second
+)
+
+var (
+	errConfigNotFound     = errors.New("config not found")
+	errConfigUnauthorized = errors.New("config server rejected the credentials")
+)
+
+// remoteConfig locates the config on a config server, every source returns it as a yaml document
+type remoteConfig struct {
+	Type        string
+	Url         string
+	Application string
+	Profile     string
+	Label       string
+	// Key is the KV key or the path below Url holding the document, config/{application}/{profile} by default
+	Key       string
+	Username  string
+	Password  string
+	Token     string
+	Retries   int
+	Backoff   time.Duration
+	Timeout   time.Duration
+	CacheFile string
+	client    *http.Client
+}
+
+func newRemoteConfig() (*remoteConfig, error) {
+	rc := &remoteConfig{
+		Type:        strings.ToLower(getEnv(configServerTypeKey, ConfigServerSpring)),
+		Url:         strings.TrimSuffix(os.Getenv(configServerUrlKey), "/"),
+		Application: getEnv(configServerApplicationKey, defaultApplicationName),
+		Profile:     getEnv(configServerProfileKey, defaultProfile),
+		Label:       os.Getenv(configServerLabelKey),
+		Username:    os.Getenv(configServerUsernameKey),
+		Password:    os.Getenv(configServerPasswordKey),
+		Token:       os.Getenv(configServerTokenKey),
+	}
+	if rc.Url == "" {
+		return nil, fmt.Errorf("%s is required", configServerUrlKey)
+	}
+	rc.Key = getEnv(configServerKeyKey, fmt.Sprintf("config/%s/%s", rc.Application, rc.Profile))
+	rc.CacheFile = getEnv(configCacheFileKey, filepath.Join(os.TempDir(), rc.Application+"-config-cache.yaml"))
+
+	var err error
+	if rc.Retries, err = getEnvInt(configServerRetriesKey, defaultConfigRetries); err != nil {
+		return nil, err
+	}
+	if rc.Backoff, err = getEnvDuration(configServerBackoffKey, defaultConfigBackoff); err != nil {
+		return nil, err
+	}
+	if rc.Timeout, err = getEnvDuration(configServerTimeoutKey, defaultConfigServerTimeout); err != nil {
+		return nil, err
+	}
+	rc.client = &http.Client{Timeout: rc.Timeout}
+
+	switch rc.Type {
+	case ConfigServerSpring, ConfigServerConsul, ConfigServerEtcd, ConfigServerFile:
+	default:
+		return nil, fmt.Errorf("unknown config server type %q", rc.Type)
+	}
+
+	return rc, nil
+}
+
+// load fetches the config with retries and caches it, the cache is used when the server stays unreachable.
+// A missing config or rejected credentials are neither retried nor replaced by the cache, they need fixing.
+func (rc *remoteConfig) load() ([]byte, error) {
+	backoff := rc.Backoff
+	var err error
+	for attempt := 0; attempt <= rc.Retries; attempt++ {
+		if attempt > 0 {
+			fmt.Printf("Config server attempt %d failed; %v, retrying in %s\n", attempt, err, backoff)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, defaultConfigMaxBackoff)
+		}
+
+		var data []byte
+		data, err = rc.fetch()
+		if err == nil {
+			rc.saveCache(data)
+			return data, nil
+		}
+		if errors.Is(err, errConfigNotFound) || errors.Is(err, errConfigUnauthorized) {
+			return nil, err
+		}
+	}
+
+	data, cacheErr := os.ReadFile(rc.CacheFile)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("config server is unreachable: %w, no cached config: %v", err, cacheErr)
+	}
+	fmt.Printf("Config server is unreachable; %v, using cached config %s\n", err, rc.CacheFile)
+
+	return data, nil
+}
+
+func (rc *remoteConfig) saveCache(data []byte) {
+	if err := os.WriteFile(rc.CacheFile, data, 0600); err != nil {
+		fmt.Printf("Config cache could not be written; %v\n", err)
+	}
+}
+
+func (rc *remoteConfig) fetch() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rc.Timeout)
+	defer cancel()
+
+	switch rc.Type {
+	case ConfigServerConsul:
+		return rc.fetchConsul(ctx)
+	case ConfigServerEtcd:
+		return rc.fetchEtcd(ctx)
+	case ConfigServerFile:
+		return rc.fetchFile()
+	default:
+		return rc.fetchSpring(ctx)
+	}
+}
+
+// fetchSpring reads the property sources of the application and profile merged into one yaml document
+func (rc *remoteConfig) fetchSpring(ctx context.Context) ([]byte, error) {
+	path := fmt.Sprintf("/%s-%s.yml", url.PathEscape(rc.Application), url.PathEscape(rc.Profile))
+	if rc.Label != "" {
+		path = "/" + url.PathEscape(rc.Label) + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.Url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	rc.authorize(req, "Authorization", "Bearer ")
+
+	return rc.do(req)
+}
+
+// fetchConsul reads the raw value of Key from the consul KV store
+func (rc *remoteConfig) fetchConsul(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.Url+"/v1/kv/"+strings.TrimPrefix(rc.Key, "/")+"?raw", nil)
+	if err != nil {
+		return nil, err
+	}
+	rc.authorize(req, "X-Consul-Token", "")
+
+	return rc.do(req)
+}
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Value string `json:"value"`
+	} `json:"kvs"`
+}
+
+// fetchEtcd reads Key through the etcd v3 JSON gateway, users authenticate for a token first
+func (rc *remoteConfig) fetchEtcd(ctx context.Context) ([]byte, error) {
+	token := rc.Token
+	if token == "" && rc.Username != "" {
+		body, _ := json.Marshal(map[string]string{"name": rc.Username, "password": rc.Password})
+		res, err := rc.post(ctx, "/v3/auth/authenticate", body, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var auth struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(res, &auth); err != nil {
+			return nil, err
+		}
+		token = auth.Token
+	}
+
+	body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(rc.Key))})
+	res, err := rc.post(ctx, "/v3/kv/range", body, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var kv etcdRangeResponse
+	if err := json.Unmarshal(res, &kv); err != nil {
+		return nil, err
+	}
+	if len(kv.Kvs) == 0 {
+		return nil, fmt.Errorf("%w: %s", errConfigNotFound, rc.Key)
+	}
+
+	return base64.StdEncoding.DecodeString(kv.Kvs[0].Value)
+}
+
+// fetchFile reads Key below the Url directory, it stands in for a KV store in tests and local runs
+func (rc *remoteConfig) fetchFile() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(strings.TrimPrefix(rc.Url, "file://"), filepath.FromSlash(rc.Key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errConfigNotFound, rc.Key)
+	}
+
+	return data, err
+}
+
+func (rc *remoteConfig) post(ctx context.Context, path string, body []byte, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rc.Url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	return rc.do(req)
+}
+
+// authorize sends Token in header, or basic auth credentials when there is no token
+func (rc *remoteConfig) authorize(req *http.Request, header, prefix string) {
+	switch {
+	case rc.Token != "":
+		req.Header.Set(header, prefix+rc.Token)
+	case rc.Username != "":
+		req.SetBasicAuth(rc.Username, rc.Password)
+	}
+}
+
+func (rc *remoteConfig) do(req *http.Request) ([]byte, error) {
+	res, err := rc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", errConfigNotFound, req.URL.Path)
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %s", errConfigUnauthorized, res.Status)
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("config server responded %s", res.Status)
+	}
+
+	return body, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// getEnvDuration reads milliseconds
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	ms, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
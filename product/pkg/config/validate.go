@@ -0,0 +1,195 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// minMaxStaleness in seconds is the smallest max staleness mongo accepts
+const minMaxStaleness = 90
+
+var logLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// problems collects validation errors with the key they belong to, so all of them are reported at once
+type problems []error
+
+func (p *problems) add(key, format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) required(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+	}
+}
+
+func (p *problems) port(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+		return
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		p.add(key, "must be a port between 1 and 65535, got %q", value)
+	}
+}
+
+func (p *problems) url(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+		return
+	}
+	if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+		p.add(key, "must be an absolute url, got %q", value)
+	}
+}
+
+func (p *problems) nonNegative(key string, value float64) {
+	if value < 0 {
+		p.add(key, "must not be negative, got %v", value)
+	}
+}
+
+func (p *problems) between(key string, value, min, max float64) {
+	if value < min || value > max {
+		p.add(key, "must be between %v and %v, got %v", min, max, value)
+	}
+}
+
+// rateRule rejects negative values and a zero burst next to a positive rate, such a bucket never holds a token
+func (p *problems) rateRule(prefix string, rate float64, burst int) {
+	p.nonNegative(prefix+"rate", rate)
+	p.nonNegative(prefix+"burst", float64(burst))
+	if rate > 0 && burst < 1 {
+		p.add(prefix+"burst", "must be at least 1 when %srate is set, got %d", prefix, burst)
+	}
+}
+
+// oneOf accepts an empty value, the code falls back to a default for those
+func (p *problems) oneOf(key, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	p.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+// Validate reports every missing or out of range value at once, one per line
+func (c *Config) Validate() error {
+	var p problems
+
+	p.port("server.port", c.Server.Port)
+	p.oneOf("server.networkType", c.Server.NetworkType, "tcp", "tcp4", "tcp6")
+	p.nonNegative("server.ctxTimeout", float64(c.Server.CtxTimeout))
+	p.nonNegative("server.healthCheckInterval", float64(c.Server.HealthCheckInterval))
+	p.nonNegative("server.drainPeriod", float64(c.Server.DrainPeriod))
+	p.nonNegative("server.shutdownTimeout", float64(c.Server.ShutdownTimeout))
+	if c.Server.TLS.Enabled {
+		p.required("server.tls.certFile", c.Server.TLS.CertFile)
+		p.required("server.tls.keyFile", c.Server.TLS.KeyFile)
+		p.oneOf("server.tls.clientAuth", c.Server.TLS.ClientAuth, "none", "request", "require", "verifyIfGiven", "requireAndVerify")
+		if strings.Contains(c.Server.TLS.ClientAuth, "Verify") || c.Server.TLS.ClientAuth == "require" {
+			p.required("server.tls.caFile", c.Server.TLS.CAFile)
+		}
+	}
+	if c.Server.Web.Enabled {
+		p.port("server.web.port", c.Server.Web.Port)
+		if c.Server.Web.Port == c.Server.Port {
+			p.add("server.web.port", "must differ from server.port")
+		}
+		if c.Server.TLS.Enabled {
+			p.oneOf("server.web.clientAuth", c.Server.Web.ClientAuth, "none", "request", "require", "verifyIfGiven", "requireAndVerify")
+			if strings.Contains(c.Server.Web.ClientAuth, "Verify") || c.Server.Web.ClientAuth == "require" {
+				p.required("server.tls.caFile", c.Server.TLS.CAFile)
+			}
+		}
+	}
+
+	p.oneOf("logger.level", c.Logger.Level, logLevels...)
+	p.oneOf("logger.encoding", c.Logger.Encoding, "json", "console")
+	for i, sink := range c.Logger.Sinks {
+		key := fmt.Sprintf("logger.sinks[%d]", i)
+		p.required(key+".type", sink.Type)
+		p.oneOf(key+".type", sink.Type, "stdout", "stderr", "file", "elasticsearch", "syslog")
+		p.oneOf(key+".level", sink.Level, logLevels...)
+		p.oneOf(key+".encoding", sink.Encoding, "json", "console")
+		switch sink.Type {
+		case "file":
+			p.required(key+".file.path", sink.File.Path)
+			p.nonNegative(key+".file.maxSize", float64(sink.File.MaxSize))
+			p.nonNegative(key+".file.maxAge", float64(sink.File.MaxAge))
+			p.nonNegative(key+".file.maxBackups", float64(sink.File.MaxBackups))
+		case "elasticsearch":
+			p.url("logger.elasticSearchUrl", c.Logger.ElasticSearchUrl)
+		}
+	}
+	p.oneOf("logger.elastic.queuePolicy", c.Logger.Elastic.QueuePolicy, "drop", "block")
+	p.nonNegative("logger.elastic.batchSize", float64(c.Logger.Elastic.BatchSize))
+	p.nonNegative("logger.elastic.flushInterval", float64(c.Logger.Elastic.FlushInterval))
+	p.nonNegative("logger.elastic.queueSize", float64(c.Logger.Elastic.QueueSize))
+	p.nonNegative("logger.elastic.maxRetries", float64(c.Logger.Elastic.MaxRetries))
+	if c.Logger.Sampling.Enabled {
+		p.between("logger.sampling.tick", float64(c.Logger.Sampling.Tick), 1, 3600000)
+		p.nonNegative("logger.sampling.initial", float64(c.Logger.Sampling.Initial))
+		p.nonNegative("logger.sampling.thereafter", float64(c.Logger.Sampling.Thereafter))
+	}
+
+	p.oneOf("jaeger.exporter", c.Jaeger.Exporter, "jaeger", "otlp", "none")
+	switch c.Jaeger.Exporter {
+	case "", "jaeger":
+		p.required("jaeger.host", c.Jaeger.Host)
+	case "otlp":
+		p.required("jaeger.otlpEndpoint", c.Jaeger.OtlpEndpoint)
+	}
+	p.oneOf("jaeger.sampler", c.Jaeger.Sampler, "always", "never", "ratio")
+	if c.Jaeger.Sampler == "ratio" {
+		p.between("jaeger.samplerRatio", c.Jaeger.SamplerRatio, 0, 1)
+	}
+
+	p.required("mongo.host", c.Mongo.Host)
+	p.port("mongo.port", c.Mongo.Port)
+	p.required("mongo.databaseName", c.Mongo.DatabaseName)
+	p.required("mongo.collectionName", c.Mongo.CollectionName)
+	p.nonNegative("mongo.slowQueryThreshold", float64(c.Mongo.SlowQueryThreshold))
+	p.oneOf("mongo.readPreference", c.Mongo.ReadPreference, "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+	p.nonNegative("mongo.maxStaleness", float64(c.Mongo.MaxStaleness))
+	if c.Mongo.MaxStaleness > 0 {
+		// the driver refuses smaller values and primary reads are never stale
+		if c.Mongo.MaxStaleness < minMaxStaleness {
+			p.add("mongo.maxStaleness", "must be at least %d seconds, got %d", minMaxStaleness, c.Mongo.MaxStaleness)
+		}
+		if c.Mongo.ReadPreference == "" || c.Mongo.ReadPreference == "primary" {
+			p.add("mongo.maxStaleness", "needs a readPreference other than primary")
+		}
+	}
+
+	p.required("metric.url", c.Metric.Url)
+
+	if c.Auth.Enabled && c.Auth.HMACSecret == "" && c.Auth.RSAPublicKeyFile == "" && c.Auth.JWKSFile == "" {
+		p.add("auth", "needs one of hmacSecret, rsaPublicKeyFile or jwksFile when enabled")
+	}
+
+	if c.RateLimit.Enabled {
+		p.rateRule("rateLimit.", c.RateLimit.Rate, c.RateLimit.Burst)
+		for i, method := range c.RateLimit.Methods {
+			key := fmt.Sprintf("rateLimit.methods[%d]", i)
+			p.required(key+".method", method.Method)
+			p.rateRule(key+".", method.Rate, method.Burst)
+		}
+	}
+
+	if c.Admin.Enabled {
+		p.port("admin.port", c.Admin.Port)
+		if c.Admin.Token == "" && !c.Auth.Enabled {
+			p.add("admin", "needs a token or auth enabled, it would be unprotected")
+		}
+	}
+
+	return errors.Join(p...)
+}
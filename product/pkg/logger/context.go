@@ -0,0 +1,20 @@
+package logger
+
+import "context"
+
+// Fields attached to request scoped loggers
+const (
+	FieldRequestId = "requestId"
+	FieldTraceId   = "traceId"
+	FieldSpanId    = "spanId"
+	FieldTenant    = "tenant"
+	FieldUser      = "user"
+	FieldMethod    = "method"
+)
+
+type loggerKey struct{}
+
+// WithContext stores logger in ctx, every FromContext call on ctx or its children returns it
+func WithContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
@@ -2,13 +2,12 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/olivere/elastic/v7"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
-	"time"
 )
 
 type Logger interface {
@@ -26,12 +25,25 @@ type Logger interface {
 	Errorf(template string, args ...interface{})
 	DPanicf(template string, args ...interface{})
 	Fatalf(template string, args ...interface{})
+
+	// With returns a logger adding the key value pairs to every line it writes
+	With(args ...interface{}) Logger
+	// FromContext returns the request scoped logger stored in ctx by WithContext, or this logger when there is none
+	FromContext(ctx context.Context) Logger
+
+	// Level is the level of the sinks without their own one, SetLevel changes it at runtime
+	Level() string
+	SetLevel(level string) error
+
+	// Sync flushes buffered log entries, call it before the process exits
+	Sync() error
 }
 
 type logger struct {
-	cfg           *config.Config
-	sugarLogger   *zap.SugaredLogger
-	elasticClient *elastic.Client
+	cfg         *config.Config
+	sugarLogger *zap.SugaredLogger
+	level       zap.AtomicLevel
+	closers     *closers
 }
 
 var loggerLevelMap = map[string]zapcore.Level{
@@ -54,131 +66,127 @@ func (l *logger) getLogLevel(cfg *config.Config) zapcore.Level {
 }
 
 func (l *logger) InitLogger() {
-	logLevel := l.getLogLevel(l.cfg)
-	logWriter := zapcore.AddSync(os.Stderr)
-
-	var encoderCfg zapcore.EncoderConfig
-	if l.cfg.Server.Mode == "Dev" {
-		encoderCfg = zap.NewDevelopmentEncoderConfig()
-	} else {
-		encoderCfg = zap.NewProductionEncoderConfig()
-	}
+	l.level = zap.NewAtomicLevelAt(l.getLogLevel(l.cfg))
+	l.closers = &closers{}
 
-	var encoder zapcore.Encoder
-	encoderCfg.LevelKey = "LEVEL"
-	encoderCfg.CallerKey = "CALLER"
-	encoderCfg.TimeKey = "TIME"
-	encoderCfg.NameKey = "NAME"
-	encoderCfg.MessageKey = "MESSAGE"
-
-	if l.cfg.Logger.Encoding == "console" {
-		encoder = zapcore.NewConsoleEncoder(encoderCfg)
-	} else {
-		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	sinks := l.cfg.Logger.Sinks
+	if len(sinks) == 0 {
+		sinks = defaultSinks
 	}
 
-	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	core := zapcore.NewCore(encoder, logWriter, zap.NewAtomicLevelAt(logLevel))
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
-
-	l.sugarLogger = logger.Sugar()
-	if err := l.sugarLogger.Sync(); err != nil {
-		l.sugarLogger.Error(err)
+	// a sink that cannot be set up is skipped so logging never stops the service from starting
+	var cores []zapcore.Core
+	var sinkErrs []error
+	for _, sink := range sinks {
+		core, closer, err := l.newSinkCore(sink)
+		if err != nil {
+			sinkErrs = append(sinkErrs, fmt.Errorf("sink: %s, error: %w", sink.Type, err))
+			continue
+		}
+		cores = append(cores, core)
+		if closer != nil {
+			l.closers.fns = append(l.closers.fns, closer)
+		}
 	}
-
-	elasticClient, err := elastic.NewClient(
-		elastic.SetURL(l.cfg.Logger.ElasticSearchUrl),
-		elastic.SetHealthcheckInterval(10*time.Second),
-		elastic.SetSniff(false),
-		elastic.SetHealthcheckTimeout(5*time.Second))
-	if err != nil {
-		l.sugarLogger.Fatal("Failed to initialize Elasticsearch client: ", err)
+	if len(cores) == 0 {
+		cores = append(cores, zapcore.NewCore(l.newEncoder(l.cfg.Logger.Encoding), zapcore.Lock(os.Stderr), l.level))
 	}
-	l.elasticClient = elasticClient
-}
 
-func (l *logger) sendLogToElasticSearch(level zapcore.Level, message string) {
-	logEntry := map[string]interface{}{
-		"level":   level.String(),
-		"message": message,
+	core := zapcore.NewTee(cores...)
+	if l.cfg.Logger.Sampling.Enabled {
+		core = newSampler(core, l.cfg.Logger.Sampling)
 	}
 
-	_, err := l.elasticClient.Index().Index("product_log_index").BodyJson(logEntry).Do(context.Background())
-	if err != nil {
-		l.sugarLogger.Error("Failed to send log entry to Elasticsearch: ", err)
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
+	l.sugarLogger = logger.Sugar()
+
+	for _, err := range sinkErrs {
+		l.sugarLogger.Errorf("Failed to initialize log %v", err)
 	}
 }
 
 func (l logger) Debug(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.Debug(args)
-	l.sendLogToElasticSearch(zapcore.DebugLevel, message)
+	l.sugarLogger.Debug(args...)
 }
 
 func (l logger) Info(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.Info(args)
-	l.sendLogToElasticSearch(zapcore.InfoLevel, message)
+	l.sugarLogger.Info(args...)
 }
 
 func (l logger) Warn(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.Warn(args)
-	l.sendLogToElasticSearch(zapcore.WarnLevel, message)
+	l.sugarLogger.Warn(args...)
 }
 
 func (l logger) Error(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.Error(args)
-	l.sendLogToElasticSearch(zapcore.ErrorLevel, message)
+	l.sugarLogger.Error(args...)
 }
 
 func (l logger) DPanic(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.DPanic(args)
-	l.sendLogToElasticSearch(zapcore.DPanicLevel, message)
+	l.sugarLogger.DPanic(args...)
 }
 
 func (l logger) Fatal(args ...interface{}) {
-	message := fmt.Sprint(args...)
-	l.sugarLogger.Fatal(args)
-	l.sendLogToElasticSearch(zapcore.FatalLevel, message)
+	l.sugarLogger.Fatal(args...)
 }
 
 func (l logger) Debugf(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.Debugf(template, args...)
-	l.sendLogToElasticSearch(zapcore.DebugLevel, message)
 }
 
 func (l logger) Infof(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.Infof(template, args...)
-	l.sendLogToElasticSearch(zapcore.InfoLevel, message)
 }
 
 func (l logger) Warnf(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.Warnf(template, args...)
-	l.sendLogToElasticSearch(zapcore.WarnLevel, message)
 }
 
 func (l logger) Errorf(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.Errorf(template, args...)
-	l.sendLogToElasticSearch(zapcore.ErrorLevel, message)
 }
 
 func (l logger) DPanicf(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.DPanicf(template, args...)
-	l.sendLogToElasticSearch(zapcore.DPanicLevel, message)
 }
 
 func (l logger) Fatalf(template string, args ...interface{}) {
-	message := fmt.Sprint(args...)
 	l.sugarLogger.Fatalf(template, args...)
-	l.sendLogToElasticSearch(zapcore.FatalLevel, message)
+}
+
+func (l *logger) With(args ...interface{}) Logger {
+	return &logger{
+		cfg:         l.cfg,
+		sugarLogger: l.sugarLogger.With(args...),
+		level:       l.level,
+		closers:     l.closers,
+	}
+}
+
+func (l *logger) FromContext(ctx context.Context) Logger {
+	if ctxLogger, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return ctxLogger
+	}
+
+	return l
+}
+
+func (l *logger) Level() string {
+	return l.level.Level().String()
+}
+
+func (l *logger) SetLevel(level string) error {
+	logLevel, exist := loggerLevelMap[level]
+	if !exist {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	l.level.SetLevel(logLevel)
+
+	return nil
+}
+
+// Sync flushes every sink and closes them afterwards, entries logged later may be lost
+func (l *logger) Sync() error {
+	return errors.Join(l.sugarLogger.Sync(), l.closers.close())
 }
 
 func NewLogger(cfg *config.Config) Logger {
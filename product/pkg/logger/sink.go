@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	SinkStdout        = "stdout"
+	SinkStderr        = "stderr"
+	SinkFile          = "file"
+	SinkElasticsearch = "elasticsearch"
+	SinkSyslog        = "syslog"
+
+	defaultSamplingTick       = time.Second
+	defaultSamplingInitial    = 100
+	defaultSamplingThereafter = 100
+)
+
+// defaultSinks are used when the config has none
+var defaultSinks = []config.LogSinkConfig{{Type: SinkStderr}, {Type: SinkElasticsearch}}
+
+// closers release the sinks once, they are shared by a logger and every logger derived from it with With
+type closers struct {
+	once sync.Once
+	fns  []func() error
+	err  error
+}
+
+func (c *closers) close() error {
+	c.once.Do(func() {
+		for _, fn := range c.fns {
+			c.err = errors.Join(c.err, fn())
+		}
+	})
+
+	return c.err
+}
+
+func (l *logger) newEncoder(encoding string) zapcore.Encoder {
+	var encoderCfg zapcore.EncoderConfig
+	if l.cfg.Server.Mode == "Dev" {
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+	} else {
+		encoderCfg = zap.NewProductionEncoderConfig()
+	}
+
+	encoderCfg.LevelKey = "LEVEL"
+	encoderCfg.CallerKey = "CALLER"
+	encoderCfg.TimeKey = "TIME"
+	encoderCfg.NameKey = "NAME"
+	encoderCfg.MessageKey = "MESSAGE"
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	if encoding == "console" {
+		return zapcore.NewConsoleEncoder(encoderCfg)
+	}
+
+	return zapcore.NewJSONEncoder(encoderCfg)
+}
+
+// newSinkCore returns the core writing to sink and the func releasing it, which may be nil
+func (l *logger) newSinkCore(sink config.LogSinkConfig) (zapcore.Core, func() error, error) {
+	var level zapcore.LevelEnabler = l.level
+	if sink.Level != "" {
+		sinkLevel, exist := loggerLevelMap[sink.Level]
+		if !exist {
+			return nil, nil, fmt.Errorf("unknown level %q", sink.Level)
+		}
+		level = zap.NewAtomicLevelAt(sinkLevel)
+	}
+
+	encoding := sink.Encoding
+	if encoding == "" {
+		encoding = l.cfg.Logger.Encoding
+	}
+
+	switch sink.Type {
+	case SinkStdout:
+		return zapcore.NewCore(l.newEncoder(encoding), zapcore.Lock(os.Stdout), level), nil, nil
+	case SinkStderr:
+		return zapcore.NewCore(l.newEncoder(encoding), zapcore.Lock(os.Stderr), level), nil, nil
+	case SinkFile:
+		if sink.File.Path == "" {
+			return nil, nil, errors.New("file sink needs a path")
+		}
+		writer := &lumberjack.Logger{
+			Filename:   sink.File.Path,
+			MaxSize:    sink.File.MaxSize,
+			MaxAge:     sink.File.MaxAge,
+			MaxBackups: sink.File.MaxBackups,
+			LocalTime:  true,
+			Compress:   sink.File.Compress,
+		}
+		return zapcore.NewCore(l.newEncoder(encoding), zapcore.AddSync(writer), level), writer.Close, nil
+	case SinkElasticsearch:
+		core, shipper, err := newElasticCore(l.cfg, level)
+		if err != nil {
+			return nil, nil, err
+		}
+		return core, shipper.close, nil
+	case SinkSyslog:
+		return newSyslogCore(sink.Syslog, l.newEncoder(encoding), level)
+	default:
+		return nil, nil, fmt.Errorf("unknown sink type %q", sink.Type)
+	}
+}
+
+// newSampler caps entries with the same level and message, sampling.Tick is in milliseconds
+func newSampler(core zapcore.Core, sampling config.LogSamplingConfig) zapcore.Core {
+	tick := time.Duration(sampling.Tick) * time.Millisecond
+	if tick <= 0 {
+		tick = defaultSamplingTick
+	}
+	initial := sampling.Initial
+	if initial <= 0 {
+		initial = defaultSamplingInitial
+	}
+	thereafter := sampling.Thereafter
+	if thereafter <= 0 {
+		thereafter = defaultSamplingThereafter
+	}
+
+	return zapcore.NewSamplerWithOptions(core, tick, initial, thereafter)
+}
@@ -0,0 +1,98 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"go.uber.org/zap/zapcore"
+	"log/syslog"
+	"strings"
+)
+
+var syslogFacilityMap = map[string]syslog.Priority{
+	"kern":   syslog.LOG_KERN,
+	"auth":   syslog.LOG_AUTH,
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"syslog": syslog.LOG_SYSLOG,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// syslogCore writes every entry with the syslog severity matching its level
+type syslogCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	writer  *syslog.Writer
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.encoder = c.encoder.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.encoder)
+	}
+
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	message := strings.TrimSuffix(buf.String(), "\n")
+	switch ent.Level {
+	case zapcore.DebugLevel:
+		return c.writer.Debug(message)
+	case zapcore.InfoLevel:
+		return c.writer.Info(message)
+	case zapcore.WarnLevel:
+		return c.writer.Warning(message)
+	case zapcore.ErrorLevel:
+		return c.writer.Err(message)
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return c.writer.Crit(message)
+	default:
+		return c.writer.Emerg(message)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
+
+func newSyslogCore(cfg config.SyslogSinkConfig, encoder zapcore.Encoder, level zapcore.LevelEnabler) (zapcore.Core, func() error, error) {
+	name := cfg.Facility
+	if name == "" {
+		name = "user"
+	}
+	facility, exist := syslogFacilityMap[name]
+	if !exist {
+		return nil, nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+
+	writer, err := syslog.Dial(cfg.Network, cfg.Address, facility|syslog.LOG_INFO, cfg.Tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &syslogCore{LevelEnabler: level, encoder: encoder, writer: writer}, writer.Close, nil
+}
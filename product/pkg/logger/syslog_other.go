@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"go.uber.org/zap/zapcore"
+	"runtime"
+)
+
+func newSyslogCore(config.SyslogSinkConfig, zapcore.Encoder, zapcore.LevelEnabler) (zapcore.Core, func() error, error) {
+	return nil, nil, fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
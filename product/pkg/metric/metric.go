@@ -0,0 +1,114 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+type Metrics interface {
+	IncHits(code, method string)
+	ObserveResponseTime(code, method string, observeTime float64)
+	IncInFlight(method string)
+	DecInFlight(method string)
+	ObserveReceivedBytes(method string, size int)
+	ObserveSentBytes(method string, size int)
+}
+
+type PrometheusMetrics struct {
+	HitsTotal     prometheus.Counter
+	Hits          *prometheus.CounterVec
+	Times         *prometheus.HistogramVec
+	InFlight      *prometheus.GaugeVec
+	ReceivedBytes *prometheus.HistogramVec
+	SentBytes     *prometheus.HistogramVec
+}
+
+func (p *PrometheusMetrics) IncHits(code, method string) {
+	p.HitsTotal.Inc()
+	p.Hits.WithLabelValues(code, method).Inc()
+}
+
+func (p *PrometheusMetrics) ObserveResponseTime(code, method string, observeTime float64) {
+	p.Times.WithLabelValues(code, method).Observe(observeTime)
+}
+
+func (p *PrometheusMetrics) IncInFlight(method string) {
+	p.InFlight.WithLabelValues(method).Inc()
+}
+
+func (p *PrometheusMetrics) DecInFlight(method string) {
+	p.InFlight.WithLabelValues(method).Dec()
+}
+
+func (p *PrometheusMetrics) ObserveReceivedBytes(method string, size int) {
+	p.ReceivedBytes.WithLabelValues(method).Observe(float64(size))
+}
+
+func (p *PrometheusMetrics) ObserveSentBytes(method string, size int) {
+	p.SentBytes.WithLabelValues(method).Observe(float64(size))
+}
+
+// messageSizeBuckets go from 64B to 4MB, the default grpc message size limit
+var messageSizeBuckets = prometheus.ExponentialBuckets(64, 4, 9)
+
+func CreateMetric(name string) (Metrics, error) {
+	var prometheusMetric PrometheusMetrics
+	prometheusMetric.HitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: name + "_hits_total",
+	})
+	if err := prometheus.Register(prometheusMetric.HitsTotal); err != nil {
+		return nil, err
+	}
+
+	prometheusMetric.Hits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_hits",
+	}, []string{"code", "method"})
+	if err := prometheus.Register(prometheusMetric.Hits); err != nil {
+		return nil, err
+	}
+
+	prometheusMetric.Times = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: name + "_duration_seconds",
+	}, []string{"code", "method"})
+	if err := prometheus.Register(prometheusMetric.Times); err != nil {
+		return nil, err
+	}
+
+	prometheusMetric.InFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_in_flight",
+	}, []string{"method"})
+	if err := prometheus.Register(prometheusMetric.InFlight); err != nil {
+		return nil, err
+	}
+
+	prometheusMetric.ReceivedBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name + "_received_bytes",
+		Buckets: messageSizeBuckets,
+	}, []string{"method"})
+	if err := prometheus.Register(prometheusMetric.ReceivedBytes); err != nil {
+		return nil, err
+	}
+
+	prometheusMetric.SentBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name + "_sent_bytes",
+		Buckets: messageSizeBuckets,
+	}, []string{"method"})
+	if err := prometheus.Register(prometheusMetric.SentBytes); err != nil {
+		return nil, err
+	}
+
+	return &prometheusMetric, nil
+}
+
+// NewServer serves every registered metric on address, the default registry already carries
+// the Go runtime and process collectors.
+func NewServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	idleTimeout     = 10 * time.Minute
+	cleanupInterval = time.Minute
+)
+
+// Rule refills Rate tokens per second into a bucket of Burst tokens, a Rate of 0 disables limiting
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type ruleSet struct {
+	defaultRule Rule
+	rules       map[string]Rule
+}
+
+// Limiter keeps a token bucket per route and client, buckets idle for a while are dropped
+type Limiter struct {
+	mutex       sync.Mutex
+	ruleSet     *ruleSet
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+func (l *Limiter) Allow(route, key string) Result {
+	now := time.Now()
+	limiter, rule := l.bucket(route, key, now)
+	if limiter == nil {
+		return Result{Allowed: true}
+	}
+
+	result := Result{Allowed: true, Limit: rule.Burst}
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); !reservation.OK() || delay > 0 {
+		reservation.CancelAt(now)
+		result.Allowed = false
+		result.RetryAfter = delay
+	}
+
+	tokens := math.Max(limiter.TokensAt(now), 0)
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((float64(rule.Burst) - tokens) / rule.Rate * float64(time.Second))
+
+	return result
+}
+
+// bucket looks the rule up under the lock Update holds, so a bucket never outlives the rules it was made for.
+// There is no bucket for routes without a limit.
+func (l *Limiter) bucket(route, key string, now time.Time) (*rate.Limiter, Rule) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	rule, exist := l.ruleSet.rules[route]
+	if !exist {
+		rule = l.ruleSet.defaultRule
+	}
+	if rule.Rate <= 0 {
+		return nil, rule
+	}
+	key = route + "|" + key
+
+	if now.Sub(l.lastCleanup) > cleanupInterval {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > idleTimeout {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	b, exist := l.buckets[key]
+	if !exist {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(rule.Rate), rule.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	return b.limiter, rule
+}
+
+// Update replaces the rules, every client starts over with a full bucket at the new rate
+func (l *Limiter) Update(defaultRule Rule, rules map[string]Rule) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.ruleSet = &ruleSet{defaultRule: defaultRule, rules: rules}
+	l.buckets = make(map[string]*bucket)
+}
+
+func NewLimiter(defaultRule Rule, rules map[string]Rule) *Limiter {
+	return &Limiter{
+		ruleSet:     &ruleSet{defaultRule: defaultRule, rules: rules},
+		buckets:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
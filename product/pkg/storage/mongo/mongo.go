@@ -4,27 +4,62 @@ import (
 	"context"
 	"fmt"
 	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"time"
 )
 
-func NewMongo(c *config.Config) (*mongo.Client, error) {
+func NewMongo(c *config.Config, logger logger.Logger) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10*c.Server.CtxTimeout))
 	defer cancel()
 
 	connStr := fmt.Sprintf("mongodb://%s:%s", c.Mongo.Host, c.Mongo.Port)
+	if c.Mongo.ReplicaSet != "" {
+		connStr = fmt.Sprintf("%s/?replicaSet=%s", connStr, c.Mongo.ReplicaSet)
+	}
+
+	monitor, err := NewMonitor(c, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts := options.Client().
+		ApplyURI(connStr).
+		SetMonitor(monitor.CommandMonitor()).
+		SetPoolMonitor(monitor.PoolMonitor())
 
-	clientOpts := options.Client().ApplyURI(connStr)
+	if c.Mongo.ReadPreference != "" {
+		readPreference, err := newReadPreference(c)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetReadPreference(readPreference)
+	}
 	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	err = client.Ping(context.Background(), nil)
+	err = client.Ping(context.Background(), readpref.Primary())
 	if err != nil {
 		return nil, err
 	}
 
 	return client, nil
 }
+
+func newReadPreference(c *config.Config) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(c.Mongo.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []readpref.Option
+	if c.Mongo.MaxStaleness > 0 {
+		opts = append(opts, readpref.WithMaxStaleness(time.Duration(c.Mongo.MaxStaleness)*time.Second))
+	}
+
+	return readpref.New(mode, opts...)
+}
@@ -0,0 +1,152 @@
+package mongo
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+const tracerName = "github.com/sefikcan/ms-grpc-sample/product/pkg/storage/mongo"
+
+type Monitor struct {
+	cfg    *config.Config
+	logger logger.Logger
+	tracer trace.Tracer
+
+	commandDuration  *prometheus.HistogramVec
+	checkoutDuration *prometheus.HistogramVec
+	connections      *prometheus.GaugeVec
+	checkedOut       *prometheus.GaugeVec
+	inflight         sync.Map
+}
+
+type inflightCommand struct {
+	span       trace.Span
+	collection string
+}
+
+// CommandMonitor records latency, slow commands and a client span for every command sent to mongo
+func (m *Monitor) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+// PoolMonitor records connection counts and how long checkouts waited for a connection,
+// failed checkouts are labelled with the reason the driver gives.
+func (m *Monitor) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: m.poolEvent,
+	}
+}
+
+func (m *Monitor) started(ctx context.Context, e *event.CommandStartedEvent) {
+	collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+
+	_, span := m.tracer.Start(ctx, collection+"."+e.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongoDB,
+			semconv.DBName(e.DatabaseName),
+			semconv.DBOperation(e.CommandName),
+			semconv.DBMongoDBCollection(collection),
+		))
+
+	m.inflight.Store(e.RequestID, inflightCommand{span: span, collection: collection})
+}
+
+func (m *Monitor) succeeded(ctx context.Context, e *event.CommandSucceededEvent) {
+	m.finished(ctx, &e.CommandFinishedEvent, nil)
+}
+
+func (m *Monitor) failed(ctx context.Context, e *event.CommandFailedEvent) {
+	m.finished(ctx, &e.CommandFinishedEvent, &e.Failure)
+}
+
+func (m *Monitor) finished(ctx context.Context, e *event.CommandFinishedEvent, failure *string) {
+	value, ok := m.inflight.LoadAndDelete(e.RequestID)
+	if !ok {
+		return
+	}
+	command := value.(inflightCommand)
+
+	status := "success"
+	if failure != nil {
+		status = "error"
+		command.span.SetStatus(codes.Error, *failure)
+	}
+	command.span.End()
+
+	m.commandDuration.WithLabelValues(e.CommandName, command.collection, status).Observe(e.Duration.Seconds())
+
+	threshold := time.Duration(m.cfg.Mongo.SlowQueryThreshold) * time.Millisecond
+	if threshold > 0 && e.Duration >= threshold {
+		m.logger.FromContext(ctx).Warnf("Slow mongo command, Command: %s, Database: %s, Collection: %s, Status: %s, Time: %s", e.CommandName, e.DatabaseName, command.collection, status, e.Duration.String())
+	}
+}
+
+func (m *Monitor) poolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		m.connections.WithLabelValues(e.Address).Inc()
+	case event.ConnectionClosed:
+		m.connections.WithLabelValues(e.Address).Dec()
+	case event.GetSucceeded:
+		m.checkedOut.WithLabelValues(e.Address).Inc()
+		m.checkoutDuration.WithLabelValues(e.Address, "success").Observe(e.Duration.Seconds())
+	case event.GetFailed:
+		m.checkoutDuration.WithLabelValues(e.Address, e.Reason).Observe(e.Duration.Seconds())
+	case event.ConnectionReturned:
+		m.checkedOut.WithLabelValues(e.Address).Dec()
+	}
+}
+
+func NewMonitor(cfg *config.Config, logger logger.Logger) (*Monitor, error) {
+	name := cfg.Metric.ServiceName
+
+	monitor := &Monitor{
+		cfg:    cfg,
+		logger: logger,
+		tracer: otel.Tracer(tracerName),
+	}
+
+	monitor.commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: name + "_mongo_command_duration_seconds",
+	}, []string{"command", "collection", "status"})
+	if err := prometheus.Register(monitor.commandDuration); err != nil {
+		return nil, err
+	}
+
+	monitor.checkoutDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: name + "_mongo_pool_checkout_duration_seconds",
+	}, []string{"address", "status"})
+	if err := prometheus.Register(monitor.checkoutDuration); err != nil {
+		return nil, err
+	}
+
+	monitor.connections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_mongo_pool_connections",
+	}, []string{"address"})
+	if err := prometheus.Register(monitor.connections); err != nil {
+		return nil, err
+	}
+
+	monitor.checkedOut = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_mongo_pool_checked_out_connections",
+	}, []string{"address"})
+	if err := prometheus.Register(monitor.checkedOut); err != nil {
+		return nil, err
+	}
+
+	return monitor, nil
+}
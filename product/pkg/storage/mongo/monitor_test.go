@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"go.mongodb.org/mongo-driver/event"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPoolMonitorObservesCheckoutWait(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Metric.ServiceName = "pool_test"
+	monitor, err := NewMonitor(cfg, logger.NewLogger(cfg))
+	if err != nil {
+		t.Fatalf("NewMonitor: %v", err)
+	}
+	poolMonitor := monitor.PoolMonitor()
+
+	const address = "localhost:27017"
+	poolMonitor.Event(&event.PoolEvent{Type: event.GetSucceeded, Address: address, Duration: 20 * time.Millisecond})
+	poolMonitor.Event(&event.PoolEvent{Type: event.GetSucceeded, Address: address, Duration: 40 * time.Millisecond})
+	poolMonitor.Event(&event.PoolEvent{Type: event.GetFailed, Address: address, Reason: event.ReasonTimedOut, Duration: time.Second})
+	poolMonitor.Event(&event.PoolEvent{Type: event.ConnectionReturned, Address: address})
+
+	tests := []struct {
+		status    string
+		wantCount uint64
+		wantSum   float64
+	}{
+		{status: "success", wantCount: 2, wantSum: 0.06},
+		{status: event.ReasonTimedOut, wantCount: 1, wantSum: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			var metric dto.Metric
+			if err := monitor.checkoutDuration.WithLabelValues(address, tt.status).(prometheus.Histogram).Write(&metric); err != nil {
+				t.Fatalf("write histogram: %v", err)
+			}
+
+			if got := metric.GetHistogram().GetSampleCount(); got != tt.wantCount {
+				t.Errorf("checkouts = %d, want %d", got, tt.wantCount)
+			}
+			if got := metric.GetHistogram().GetSampleSum(); math.Abs(got-tt.wantSum) > 1e-9 {
+				t.Errorf("checkout wait = %vs, want %vs", got, tt.wantSum)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(monitor.checkedOut.WithLabelValues(address)); got != 1 {
+		t.Errorf("checked out connections = %v, want 1", got)
+	}
+}
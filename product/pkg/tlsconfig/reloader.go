@@ -0,0 +1,213 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var clientAuthMap = map[string]tls.ClientAuthType{
+	"":                 tls.NoClientCert,
+	"none":             tls.NoClientCert,
+	"request":          tls.RequestClientCert,
+	"require":          tls.RequireAnyClientCert,
+	"verifyIfGiven":    tls.VerifyClientCertIfGiven,
+	"requireAndVerify": tls.RequireAndVerifyClientCert,
+}
+
+// Reloader keeps the key pair and CA pool in memory and reloads them when their files change,
+// so certificates can be rotated without a restart.
+type Reloader struct {
+	cfg        config.TLSConfig
+	logger     logger.Logger
+	clientAuth tls.ClientAuthType
+	watcher    *fsnotify.Watcher
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	caPool      *x509.CertPool
+}
+
+func (r *Reloader) load() error {
+	var certificate *tls.Certificate
+	if r.cfg.CertFile != "" || r.cfg.KeyFile != "" {
+		keyPair, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		certificate = &keyPair
+	}
+
+	var caPool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		ca, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return err
+		}
+
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in %s", r.cfg.CAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	r.certificate = certificate
+	r.caPool = caPool
+	r.mutex.Unlock()
+
+	return nil
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.certificate, r.caPool
+}
+
+// watch reloads on any change in the directories of the files, secret mounts replace files by renaming
+func (r *Reloader) watch() {
+	files := map[string]bool{}
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if file != "" {
+			files[filepath.Clean(file)] = true
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if !files[filepath.Clean(e.Name)] || e.Has(fsnotify.Chmod) {
+				continue
+			}
+
+			if err := r.load(); err != nil {
+				r.logger.Errorf("TLS certificates reload failed, keeping the previous ones: %v", err)
+				continue
+			}
+			r.logger.Infof("TLS certificates reloaded after change of %s", e.Name)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.logger.Errorf("TLS certificates watch error: %v", err)
+		}
+	}
+}
+
+// ServerConfig verifies clients against the CA file according to the configured client auth and offers nextProtos
+// in ALPN, every handshake gets a config of its own so NextProtos set on the returned one later are not used.
+func (r *Reloader) ServerConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, caPool := r.current()
+			if certificate == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certificate},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    caPool,
+				NextProtos:   nextProtos,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig presents the key pair when the server asks for it and verifies the server against the CA file,
+// or the system roots when there is none.
+func (r *Reloader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.cfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := r.current()
+			if certificate == nil {
+				return &tls.Certificate{}, nil
+			}
+
+			return certificate, nil
+		},
+		// The default verification only knows the CA pool of the moment the config was built,
+		// VerifyConnection does the same checks against the reloaded pool instead.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+
+			_, caPool := r.current()
+			opts := x509.VerifyOptions{
+				DNSName:       cs.ServerName,
+				Roots:         caPool,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, intermediate := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(intermediate)
+			}
+
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}
+}
+
+func (r *Reloader) Close() error {
+	return r.watcher.Close()
+}
+
+func NewReloader(cfg config.TLSConfig, logger logger.Logger) (*Reloader, error) {
+	clientAuth, exist := clientAuthMap[cfg.ClientAuth]
+	if !exist {
+		return nil, fmt.Errorf("unknown TLS client auth %q", cfg.ClientAuth)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	reloader := &Reloader{
+		cfg:        cfg,
+		logger:     logger,
+		clientAuth: clientAuth,
+		watcher:    watcher,
+	}
+
+	if err := reloader.load(); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	dirs := map[string]bool{}
+	for _, file := range []string{cfg.CertFile, cfg.KeyFile, cfg.CAFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+
+	go reloader.watch()
+
+	return reloader, nil
+}
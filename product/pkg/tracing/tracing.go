@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/config"
+	"github.com/sefikcan/ms-grpc-sample/product/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"net"
+)
+
+// NewTracerProvider installs the global tracer provider and the W3C trace context propagator.
+// Shutdown flushes the spans that are still buffered.
+func NewTracerProvider(cfg *config.Config, logger logger.Logger) (*sdktrace.TracerProvider, error) {
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.Jaeger.ServiceName),
+		semconv.ServiceVersion(cfg.Server.AppVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	if cfg.Jaeger.LogSpans {
+		opts = append(opts, sdktrace.WithSpanProcessor(&logSpanProcessor{logger: logger}))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider, nil
+}
+
+func newExporter(cfg *config.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Jaeger.Exporter {
+	case "", "jaeger":
+		host, port, err := net.SplitHostPort(cfg.Jaeger.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		return jaeger.New(jaeger.WithAgentEndpoint(jaeger.WithAgentHost(host), jaeger.WithAgentPort(port)))
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Jaeger.OtlpEndpoint)}
+		if cfg.Jaeger.OtlpInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptracegrpc.New(context.Background(), opts...)
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Jaeger.Exporter)
+	}
+}
+
+func newSampler(cfg *config.Config) (sdktrace.Sampler, error) {
+	switch cfg.Jaeger.Sampler {
+	case "", "always":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "never":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "ratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Jaeger.SamplerRatio)), nil
+	default:
+		return nil, fmt.Errorf("unknown trace sampler %q", cfg.Jaeger.Sampler)
+	}
+}
+
+// logSpanProcessor logs finished spans for local debugging
+type logSpanProcessor struct {
+	logger logger.Logger
+}
+
+func (p *logSpanProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (p *logSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.logger.Debugf("Span: %s, TraceId: %s, SpanId: %s, ParentSpanId: %s, Status: %s, Time: %s",
+		s.Name(), s.SpanContext().TraceID(), s.SpanContext().SpanID(), s.Parent().SpanID(), s.Status().Code, s.EndTime().Sub(s.StartTime()).String())
+}
+
+func (p *logSpanProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (p *logSpanProcessor) ForceFlush(context.Context) error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// HeaderXRequestID is the metadata key the request id is read from and echoed back on
+const HeaderXRequestID = "x-request-id"
+
+// HeaderXTenantID is the metadata key the tenant of the caller is read from
+const HeaderXTenantID = "x-tenant-id"
+
+type requestIdKey struct{}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+func GetRequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+func GetPeerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return p.Addr.String()
+}
+
+// GetTraceId returns the trace id of the span in ctx, empty when ctx is not traced
+func GetTraceId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
+func GetSpanId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasSpanID() {
+		return ""
+	}
+
+	return spanContext.SpanID().String()
+}
+
+// GetTenant returns the tenant the caller sent in the x-tenant-id metadata, empty when there is none
+func GetTenant(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(HeaderXTenantID); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,8 @@
+package proto
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 definition protoc-gen-openapiv2 generates from the google.api.http annotations
+//
+//go:embed product.swagger.json
+var OpenAPI []byte
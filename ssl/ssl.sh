@@ -0,0 +1,29 @@
+#!/usr/bin/env bash
+# Generates a local CA plus server and client certificates signed by it, for development only.
+# SERVER_CN and SERVER_SAN can be overridden, e.g. SERVER_SAN="DNS:product_app" ./ssl/ssl.sh
+set -euo pipefail
+
+cd "$(dirname "$0")"
+
+SERVER_CN=${SERVER_CN:-localhost}
+SERVER_SAN=${SERVER_SAN:-DNS:localhost,DNS:product_app,DNS:bff_app,IP:127.0.0.1}
+CLIENT_CN=${CLIENT_CN:-bff}
+DAYS=${DAYS:-365}
+
+# Certificate authority
+openssl genrsa -out ca.key 4096
+openssl req -x509 -new -key ca.key -sha256 -days "${DAYS}" -subj "/CN=ms-grpc-sample dev CA" -out ca.crt
+
+# Server certificate used by the product service and the BFF https listener
+openssl genrsa -out server.key 2048
+openssl req -new -key server.key -subj "/CN=${SERVER_CN}" -out server.csr
+printf "subjectAltName=%s\nextendedKeyUsage=serverAuth\n" "${SERVER_SAN}" > server.ext
+openssl x509 -req -in server.csr -CA ca.crt -CAkey ca.key -CAcreateserial -days "${DAYS}" -sha256 -extfile server.ext -out server.crt
+
+# Client certificate the BFF presents to the product service for mutual TLS
+openssl genrsa -out client.key 2048
+openssl req -new -key client.key -subj "/CN=${CLIENT_CN}" -out client.csr
+printf "extendedKeyUsage=clientAuth\n" > client.ext
+openssl x509 -req -in client.csr -CA ca.crt -CAkey ca.key -CAcreateserial -days "${DAYS}" -sha256 -extfile client.ext -out client.crt
+
+rm -f server.ext client.ext